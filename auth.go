@@ -0,0 +1,105 @@
+package pho
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ErrMissingToken is returned when the handshake request does not carry a
+// token
+var ErrMissingToken = errors.New("The authentication token is missing")
+
+// principalCtxKey is the context key of the authenticated principal
+var principalCtxKey = &contextKey{"Principal"}
+
+// Principal is the identity of the authenticated client
+type Principal interface{}
+
+// AuthenticateFunc authenticates the handshake request before it is upgraded.
+// The handshake is rejected when it returns an error. The status code of
+// a *ResponseError is sent to the client, otherwise 401 Unauthorized.
+type AuthenticateFunc func(r *http.Request) (Principal, error)
+
+// TokenExtractor extracts the authentication token from the handshake
+// request. It returns an empty string when the token is missing.
+type TokenExtractor func(r *http.Request) string
+
+// TokenAuth returns an AuthenticateFunc that authenticates the token found by
+// the first extractor that finds one
+func TokenAuth(fn func(token string) (Principal, error), extractors ...TokenExtractor) AuthenticateFunc {
+	return func(r *http.Request) (Principal, error) {
+		for _, extract := range extractors {
+			if token := extract(r); token != "" {
+				return fn(token)
+			}
+		}
+
+		return nil, ErrMissingToken
+	}
+}
+
+// BearerToken extracts the token from the "Authorization: Bearer" header
+func BearerToken(r *http.Request) string {
+	const prefix = "bearer "
+
+	header := r.Header.Get("Authorization")
+	if len(header) > len(prefix) && strings.EqualFold(header[:len(prefix)], prefix) {
+		return strings.TrimSpace(header[len(prefix):])
+	}
+
+	return ""
+}
+
+// CookieToken extracts the token from the cookie with the given name
+func CookieToken(name string) TokenExtractor {
+	return func(r *http.Request) string {
+		cookie, err := r.Cookie(name)
+		if err != nil {
+			return ""
+		}
+		return cookie.Value
+	}
+}
+
+// QueryToken extracts the token from the query parameter with the given name
+func QueryToken(name string) TokenExtractor {
+	return func(r *http.Request) string {
+		return r.URL.Query().Get(name)
+	}
+}
+
+// GetPrincipal returns the principal of the socket that sent the request
+func GetPrincipal(ctx context.Context) Principal {
+	return ctx.Value(principalCtxKey)
+}
+
+// SocketPrincipal returns the principal of the socket
+func SocketPrincipal(w SocketWriter) Principal {
+	return w.Metadata()[MetadataPrincipalKey]
+}
+
+// authenticate runs the authentication hook of the mux. It reports the error
+// and writes the rejection to the handshake response when the hook fails.
+func (m *Mux) authenticate(w http.ResponseWriter, r *http.Request) (Principal, bool) {
+	if m.authenticateFn == nil {
+		return nil, true
+	}
+
+	principal, err := m.authenticateFn(r)
+	if err != nil {
+		code := http.StatusUnauthorized
+
+		var rerr *ResponseError
+		if errors.As(err, &rerr) {
+			code = rerr.StatusCode
+		}
+
+		m.handleError(err)
+		http.Error(w, err.Error(), code)
+		return nil, false
+	}
+
+	return principal, true
+}
@@ -0,0 +1,114 @@
+package pho_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/svett/pho"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Authenticate", func() {
+	var (
+		router *pho.Mux
+		server *httptest.Server
+		url    string
+	)
+
+	BeforeEach(func() {
+		router = pho.NewMux()
+		server = httptest.NewServer(router)
+		url = fmt.Sprintf("ws://%s", server.Listener.Addr().String())
+
+		router.Authenticate(pho.TokenAuth(func(token string) (pho.Principal, error) {
+			switch token {
+			case "secret":
+				return "jack", nil
+			case "banned":
+				return nil, &pho.ResponseError{StatusCode: http.StatusForbidden, Message: "banned"}
+			default:
+				return nil, fmt.Errorf("invalid token")
+			}
+		}, pho.BearerToken, pho.QueryToken("token")))
+	})
+
+	AfterEach(func() {
+		router.Close()
+		server.Close()
+	})
+
+	It("exposes the principal to the requests", func() {
+		router.On("whoami", func(w pho.SocketWriter, r *pho.Request) {
+			defer GinkgoRecover()
+			Expect(pho.SocketPrincipal(w)).To(Equal("jack"))
+			Expect(w.Write("whoami", http.StatusOK, []byte(fmt.Sprintf("%q", pho.GetPrincipal(r.Context()))))).To(Succeed())
+		})
+
+		client, err := pho.Dial(url, http.Header{"Authorization": {"Bearer secret"}})
+		Expect(err).To(BeNil())
+		defer client.Close()
+
+		response, err := client.Call(context.Background(), "whoami", []byte(`""`))
+		Expect(err).To(BeNil())
+		Expect(string(response.Payload)).To(Equal(`"jack"`))
+	})
+
+	It("rejects the handshake without a token", func() {
+		_, response, err := websocket.DefaultDialer.Dial(url, nil)
+		Expect(err).To(Equal(websocket.ErrBadHandshake))
+		Expect(response.StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("rejects the handshake with an invalid token", func() {
+		_, response, err := websocket.DefaultDialer.Dial(url+"?token=wrong", nil)
+		Expect(err).To(Equal(websocket.ErrBadHandshake))
+		Expect(response.StatusCode).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("reports the errors of the rejected handshakes", func() {
+		errs := make(chan error, 1)
+		router.OnError(func(err error) {
+			errs <- err
+		})
+
+		_, _, err := websocket.DefaultDialer.Dial(url+"?token=wrong", nil)
+		Expect(err).To(Equal(websocket.ErrBadHandshake))
+		Eventually(errs).Should(Receive(MatchError("invalid token")))
+	})
+
+	It("rejects the handshake with the status code of the error", func() {
+		_, response, err := websocket.DefaultDialer.Dial(url+"?token=banned", nil)
+		Expect(err).To(Equal(websocket.ErrBadHandshake))
+		Expect(response.StatusCode).To(Equal(http.StatusForbidden))
+	})
+
+	Describe("token extractors", func() {
+		It("extracts the bearer token", func() {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Header.Set("Authorization", "bearer secret")
+			Expect(pho.BearerToken(r)).To(Equal("secret"))
+
+			r.Header.Set("Authorization", "Basic secret")
+			Expect(pho.BearerToken(r)).To(BeEmpty())
+		})
+
+		It("extracts the cookie token", func() {
+			r := httptest.NewRequest("GET", "/", nil)
+			Expect(pho.CookieToken("session")(r)).To(BeEmpty())
+
+			r.AddCookie(&http.Cookie{Name: "session", Value: "secret"})
+			Expect(pho.CookieToken("session")(r)).To(Equal("secret"))
+		})
+
+		It("extracts the query token", func() {
+			r := httptest.NewRequest("GET", "/?access_token=secret", nil)
+			Expect(pho.QueryToken("access_token")(r)).To(Equal("secret"))
+		})
+	})
+})
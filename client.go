@@ -1,12 +1,15 @@
 package pho
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
@@ -18,19 +21,82 @@ const (
 
 	// Time allowed to read the next message from the peer.
 	ReadDeadline = 60 * time.Second
+
+	// Time allowed to read the pong message after a ping.
+	PongTimeout = 10 * time.Second
+
+	// Period to send pings to the peer. Must be less than ReadDeadline.
+	PingInterval = (ReadDeadline * 9) / 10
+
+	// Time allowed to the peer to answer a close frame.
+	CloseTimeout = 10 * time.Second
 )
 
+// ErrClientClosed is returned by Call when the connection is closed before
+// the response arrives
+var ErrClientClosed = errors.New("The client connection is closed")
+
 // OnResponseFunc is a callback function that occurs when response is received
 type OnResponseFunc func(r *Response)
 
+// OnConnectionLostFunc is a callback function that occurs when the client
+// loses the connection to the server
+type OnConnectionLostFunc func(err error)
+
+// OnReconnectFunc is a callback function that occurs when the client
+// restores the connection after the provided number of attempts
+type OnReconnectFunc func(attempts int)
+
+// ClientOptions provides the client options
+type ClientOptions struct {
+	// Header is sent with every handshake request
+	Header http.Header
+	// Reconnect enables automatic reconnection when it is provided
+	Reconnect *ReconnectPolicy
+	// PingInterval is the period of sending pings to the server
+	// (zero disables the pings)
+	PingInterval time.Duration
+	// PongTimeout is the time allowed to the server to answer a ping
+	PongTimeout time.Duration
+	// ReadDeadline is the time allowed to read the next message
+	ReadDeadline time.Duration
+	// Codec encodes the messages (defaults to JSONCodec). It is requested
+	// from the server through the Sec-WebSocket-Protocol header.
+	Codec Codec
+	// MaxMessageSize is the maximum size in bytes of a message read from the
+	// server (zero means no limit). The connection is closed with close code
+	// 1009 when a message exceeds it.
+	MaxMessageSize int64
+	// Interceptor intercepts every call and every request sent by Do or
+	// Write (ex. to trace them)
+	Interceptor CallInterceptor
+}
+
+// CallInvoker sends the request of a call and waits for its response
+type CallInvoker func(ctx context.Context, request *Request) (*Response, error)
+
+// CallInterceptor intercepts a call. It can modify the request (ex. its
+// header) and must call invoke to send it. For the requests sent by Do and
+// Write, invoke returns a nil response once the request is written.
+type CallInterceptor func(ctx context.Context, request *Request, invoke CallInvoker) (*Response, error)
+
 // A Client is an RPC client.
 type Client struct {
-	rw           *sync.RWMutex
-	conn         *websocket.Conn
-	stopChan     chan struct{}
-	handlers     map[string]OnResponseFunc
-	onResponseFn OnResponseFunc
-	onErrorFn    OnErrorFunc
+	rw             *sync.RWMutex
+	wmu            *sync.Mutex
+	url            string
+	options        *ClientOptions
+	conn           *websocket.Conn
+	heartbeat      *heartbeat
+	codec          Codec
+	stopChan       chan struct{}
+	handlers       map[string]OnResponseFunc
+	calls          map[string]chan *Response
+	sequence       uint64
+	onResponseFn   OnResponseFunc
+	onErrorFn      OnErrorFunc
+	onDisconnectFn OnConnectionLostFunc
+	onReconnectFn  OnReconnectFunc
 }
 
 // Dial creates a new client connection. Use requestHeader to specify the
@@ -38,25 +104,44 @@ type Client struct {
 // Use the response.Header to get the selected subprotocol
 // (Sec-WebSocket-Protocol) and cookies (Set-Cookie).
 func Dial(url string, header http.Header) (*Client, error) {
-	conn, _, err := websocket.DefaultDialer.Dial(url, header)
-	if err != nil {
-		return nil, err
+	return DialWithOptions(url, &ClientOptions{Header: header})
+}
+
+// DialWithOptions creates a new client connection configured by the
+// provided options.
+func DialWithOptions(url string, options *ClientOptions) (*Client, error) {
+	if options == nil {
+		options = &ClientOptions{}
 	}
 
-	conn.SetReadLimit(0)
-	conn.SetPongHandler(func(string) error {
-		return conn.SetReadDeadline(time.Now().Add(ReadDeadline))
-	})
+	codec := options.Codec
+	if codec == nil {
+		codec = JSONCodec
+	}
 
 	client := &Client{
 		rw:       &sync.RWMutex{},
-		conn:     conn,
+		wmu:      &sync.Mutex{},
+		url:      url,
+		options:  options,
+		codec:    codec,
 		stopChan: make(chan struct{}),
 		handlers: map[string]OnResponseFunc{},
+		calls:    map[string]chan *Response{},
 	}
 
+	conn, err := client.dial()
+	if err != nil {
+		return nil, err
+	}
+
+	client.connect(conn)
 	go client.run()
 
+	if options.PingInterval > 0 {
+		go client.keepalive()
+	}
+
 	return client, nil
 }
 
@@ -68,22 +153,94 @@ func (c *Client) Write(requestType string, body []byte) error {
 	})
 }
 
-// Do sends an RPC request and returns an RPC response
+// Do sends an RPC request without waiting for its response. The request
+// goes through the interceptor of the client.
 func (c *Client) Do(req *Request) error {
+	if c.options.Interceptor == nil {
+		return c.send(req)
+	}
+
+	_, err := c.options.Interceptor(context.Background(), req, func(ctx context.Context, request *Request) (*Response, error) {
+		return nil, c.send(request)
+	})
+
+	return err
+}
+
+// send writes the request to the connection
+func (c *Client) send(req *Request) error {
 	if req.Type == "" {
 		return fmt.Errorf("The Request does not have verb")
 	}
 
-	w, err := c.conn.NextWriter(websocket.BinaryMessage)
+	data, err := c.codec.Marshal(req)
 	if err != nil {
 		return err
 	}
-	err = json.NewEncoder(w).Encode(req)
-	errClose := w.Close()
-	if err != nil && errClose != nil {
-		err = fmt.Errorf("%v: %v", err, errClose)
+
+	// the connection supports only one concurrent writer
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	conn := c.connection()
+	if err := conn.SetWriteDeadline(time.Now().Add(WriteDeadline)); err != nil {
+		return err
+	}
+
+	return conn.WriteMessage(websocket.BinaryMessage, data)
+}
+
+// Call sends an RPC request and blocks until the server responds to it or
+// the context is done. When the context is done, Call asks the server to
+// cancel the request. An error response is returned as *ResponseError.
+func (c *Client) Call(ctx context.Context, verb string, body []byte) (*Response, error) {
+	request := &Request{
+		ID:   strconv.FormatUint(atomic.AddUint64(&c.sequence, 1), 10),
+		Type: verb,
+		Body: body,
+	}
+
+	if c.options.Interceptor != nil {
+		return c.options.Interceptor(ctx, request, c.invoke)
+	}
+
+	return c.invoke(ctx, request)
+}
+
+// invoke sends the request and waits for its response
+func (c *Client) invoke(ctx context.Context, request *Request) (*Response, error) {
+	id := request.ID
+	done := make(chan *Response, 1)
+
+	c.rw.Lock()
+	c.calls[id] = done
+	c.rw.Unlock()
+
+	defer func() {
+		c.rw.Lock()
+		delete(c.calls, id)
+		c.rw.Unlock()
+	}()
+
+	if err := c.send(request); err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-ctx.Done():
+		c.handleError(c.send(&Request{ID: id, Type: CancelType}))
+		return nil, ctx.Err()
+	case response, ok := <-done:
+		if !ok {
+			return nil, ErrClientClosed
+		}
+
+		if response.Type == ErrorType {
+			return nil, c.responseError(response)
+		}
+
+		return response, nil
 	}
-	return err
 }
 
 func (c *Client) OnResponse(fn OnResponseFunc) {
@@ -98,6 +255,20 @@ func (c *Client) OnError(fn OnErrorFunc) {
 	c.onErrorFn = fn
 }
 
+// OnDisconnect register a callback function called when the connection is lost
+func (c *Client) OnDisconnect(fn OnConnectionLostFunc) {
+	c.rw.Lock()
+	defer c.rw.Unlock()
+	c.onDisconnectFn = fn
+}
+
+// OnReconnect register a callback function called when the connection is restored
+func (c *Client) OnReconnect(fn OnReconnectFunc) {
+	c.rw.Lock()
+	defer c.rw.Unlock()
+	c.onReconnectFn = fn
+}
+
 // On register callback function called when response with provided verb occurs
 func (c *Client) On(verb string, fn OnResponseFunc) {
 	c.rw.Lock()
@@ -110,31 +281,72 @@ func (c *Client) Close() {
 	close(c.stopChan)
 }
 
-// run listens for server responses
+// run listens for server responses and restores the connection when it
+// is lost
 func (c *Client) run() {
+	for {
+		err := c.listen()
+		c.closeCalls()
+
+		if err == nil {
+			return
+		}
+
+		c.rw.RLock()
+		onDisconnectFn := c.onDisconnectFn
+		c.rw.RUnlock()
+
+		if onDisconnectFn != nil {
+			onDisconnectFn(err)
+		}
+
+		if c.options.Reconnect == nil || !c.reconnect() {
+			return
+		}
+	}
+}
+
+// listen reads server responses until the client is closed or the
+// connection fails
+func (c *Client) listen() error {
+	c.rw.RLock()
+	conn, heartbeat := c.conn, c.heartbeat
+	c.rw.RUnlock()
+
 	for {
 		select {
 		case <-c.stopChan:
-			c.handleError(c.conn.WriteControl(websocket.CloseMessage, []byte{}, time.Now().Add(30*time.Second)))
-			c.handleError(c.conn.Close())
-			return
+			c.handleError(conn.WriteControl(websocket.CloseMessage, []byte{}, time.Now().Add(30*time.Second)))
+			c.handleError(conn.Close())
+			return nil
 		default:
-			if err := c.conn.SetReadDeadline(time.Now().Add(ReadDeadline)); err != nil {
+			if err := heartbeat.extend(); err != nil {
 				continue
 			}
 
-			msgType, reader, err := c.conn.NextReader()
+			msgType, reader, err := conn.NextReader()
 			if err != nil {
-				c.handleError(c.conn.Close())
-				return
+				c.handleError(conn.Close())
+
+				select {
+				case <-c.stopChan:
+					return nil
+				default:
+					return heartbeat.reason(err)
+				}
 			}
 
 			if msgType != websocket.TextMessage && msgType != websocket.BinaryMessage {
 				continue
 			}
 
+			data, err := ioutil.ReadAll(reader)
+			if err != nil {
+				continue
+			}
+
 			response := &Response{}
-			if err := json.NewDecoder(reader).Decode(response); err != nil {
+			if err := c.codec.Unmarshal(data, response); err != nil {
 				continue
 			}
 
@@ -143,21 +355,23 @@ func (c *Client) run() {
 				c.onResponseFn(response)
 			}
 
-			handler, ok := c.handlers[response.Type]
-
-			if response.Type == ErrorType {
-				socketErr := &SocketError{}
+			if done, ok := c.calls[response.ID]; ok && response.ID != "" {
+				c.rw.RUnlock()
 
-				if err := json.Unmarshal(response.Payload, socketErr); err != nil {
-					c.handleError(err)
-					continue
+				select {
+				case done <- response:
+				default:
 				}
-
-				c.handleError(errors.New(socketErr.Error))
+				continue
 			}
 
+			handler, ok := c.handlers[response.Type]
 			c.rw.RUnlock()
 
+			if response.Type == ErrorType {
+				c.handleError(c.responseError(response))
+			}
+
 			if ok {
 				handler(response)
 			}
@@ -166,6 +380,117 @@ func (c *Client) run() {
 	}
 }
 
+// reconnect dials the server until it succeeds or the reconnect policy
+// is exhausted
+func (c *Client) reconnect() bool {
+	var (
+		policy  = c.options.Reconnect
+		started = time.Now()
+	)
+
+	for attempt := 1; !policy.exhausted(attempt, time.Since(started)); attempt++ {
+		select {
+		case <-c.stopChan:
+			return false
+		case <-time.After(policy.delay(attempt)):
+		}
+
+		conn, err := c.dial()
+		if err != nil {
+			c.handleError(err)
+			continue
+		}
+
+		c.connect(conn)
+
+		c.rw.RLock()
+		onReconnectFn := c.onReconnectFn
+		c.rw.RUnlock()
+
+		if onReconnectFn != nil {
+			onReconnectFn(attempt)
+		}
+
+		return true
+	}
+
+	c.handleError(fmt.Errorf("The client failed to reconnect to %q", c.url))
+	return false
+}
+
+// dial opens a new connection to the server
+func (c *Client) dial() (*websocket.Conn, error) {
+	dialer := *websocket.DefaultDialer
+
+	if c.options.Codec != nil {
+		dialer.Subprotocols = []string{c.codec.Name()}
+	}
+
+	conn, _, err := dialer.Dial(c.url, c.options.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.options.Codec != nil && conn.Subprotocol() != c.codec.Name() {
+		conn.Close()
+		return nil, fmt.Errorf("The server does not support %q codec", c.codec.Name())
+	}
+
+	if c.options.MaxMessageSize > 0 {
+		conn.SetReadLimit(c.options.MaxMessageSize)
+	}
+
+	return conn, nil
+}
+
+// connect makes the provided connection current
+func (c *Client) connect(conn *websocket.Conn) {
+	heartbeat := newHeartbeat(conn, c.options.ReadDeadline, c.options.PongTimeout)
+
+	c.rw.Lock()
+	c.conn = conn
+	c.heartbeat = heartbeat
+	c.rw.Unlock()
+}
+
+// keepalive pings the server until the client is closed
+func (c *Client) keepalive() {
+	ticker := time.NewTicker(c.options.PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.stopChan:
+			return
+		case <-ticker.C:
+			c.rw.RLock()
+			heartbeat := c.heartbeat
+			c.rw.RUnlock()
+
+			// a failed ping is detected by the reader as well
+			heartbeat.ping()
+		}
+	}
+}
+
+// connection returns the current connection
+func (c *Client) connection() *websocket.Conn {
+	c.rw.RLock()
+	defer c.rw.RUnlock()
+	return c.conn
+}
+
+// closeCalls releases all pending calls
+func (c *Client) closeCalls() {
+	c.rw.Lock()
+	defer c.rw.Unlock()
+
+	for id, done := range c.calls {
+		close(done)
+		delete(c.calls, id)
+	}
+}
+
 func (c *Client) handleError(err error) {
 	if err == nil {
 		return
@@ -175,3 +500,19 @@ func (c *Client) handleError(err error) {
 		c.onErrorFn(err)
 	}
 }
+
+// responseError converts an error response to *ResponseError
+func (c *Client) responseError(response *Response) error {
+	socketErr := &SocketError{}
+
+	if err := c.codec.Unmarshal(response.Payload, socketErr); err != nil {
+		return err
+	}
+
+	return &ResponseError{
+		StatusCode: response.StatusCode,
+		Message:    socketErr.Error,
+		Header:     response.Header,
+		Fields:     socketErr.Fields,
+	}
+}
@@ -1,6 +1,7 @@
 package pho_test
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -148,6 +149,223 @@ var _ = Describe("Client", func() {
 		Eventually(func() int { return cntOnResponse }).Should(Equal(2))
 	})
 
+	Context("when a call is performed", func() {
+		var (
+			router *pho.Mux
+			server *httptest.Server
+			client *pho.Client
+		)
+
+		BeforeEach(func() {
+			router = pho.NewMux()
+			server = httptest.NewServer(router)
+
+			var err error
+			client, err = pho.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+			Expect(err).To(BeNil())
+		})
+
+		AfterEach(func() {
+			client.Close()
+			router.Close()
+			server.Close()
+		})
+
+		It("returns the response for the request", func() {
+			router.On("echo", func(w pho.SocketWriter, r *pho.Request) {
+				defer GinkgoRecover()
+				Expect(r.ID).NotTo(BeEmpty())
+				Expect(w.Write("echo", http.StatusOK, r.Body)).To(Succeed())
+			})
+
+			response, err := client.Call(context.Background(), "echo", []byte(`"jack"`))
+			Expect(err).To(BeNil())
+			Expect(response.ID).NotTo(BeEmpty())
+			Expect(response.Type).To(Equal("echo"))
+			Expect(string(response.Payload)).To(Equal(`"jack"`))
+		})
+
+		It("does not dispatch the response to the verb handlers", func() {
+			router.On("echo", func(w pho.SocketWriter, r *pho.Request) {
+				Expect(w.Write("echo", http.StatusOK, r.Body)).To(Succeed())
+			})
+
+			cnt := 0
+			client.On("echo", func(resp *pho.Response) {
+				cnt++
+			})
+
+			_, err := client.Call(context.Background(), "echo", []byte(`"jack"`))
+			Expect(err).To(BeNil())
+			Consistently(func() int { return cnt }).Should(Equal(0))
+		})
+
+		Context("when the server responds with an error", func() {
+			It("returns the error", func() {
+				router.On("echo", func(w pho.SocketWriter, r *pho.Request) {
+					Expect(w.WriteError(fmt.Errorf("oh no!"), http.StatusConflict)).To(Succeed())
+				})
+
+				response, err := client.Call(context.Background(), "echo", []byte(`"jack"`))
+				Expect(response).To(BeNil())
+				Expect(err).To(Equal(&pho.ResponseError{
+					StatusCode: http.StatusConflict,
+					Message:    "oh no!",
+				}))
+			})
+		})
+
+		Context("when the context deadline is exceeded", func() {
+			It("returns the error", func() {
+				router.On("echo", func(w pho.SocketWriter, r *pho.Request) {})
+
+				ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+				defer cancel()
+
+				response, err := client.Call(ctx, "echo", []byte(`"jack"`))
+				Expect(response).To(BeNil())
+				Expect(err).To(Equal(context.DeadlineExceeded))
+			})
+		})
+
+	})
+
+	Context("when the connection is closed before the response", func() {
+		It("returns the error", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+
+				conn, err := websocket.Upgrade(w, r, nil, 1024, 1024)
+				Expect(err).To(BeNil())
+
+				_, _, err = conn.NextReader()
+				Expect(err).To(BeNil())
+				Expect(conn.Close()).To(Succeed())
+			}))
+
+			defer server.Close()
+
+			client, err := pho.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+			Expect(err).To(BeNil())
+
+			response, err := client.Call(context.Background(), "echo", []byte(`"jack"`))
+			Expect(response).To(BeNil())
+			Expect(err).To(Equal(pho.ErrClientClosed))
+		})
+	})
+
+	Context("when the message size is limited", func() {
+		It("closes the connection with 1009 Message Too Big", func() {
+			closed := make(chan error, 1)
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+
+				conn, err := websocket.Upgrade(w, r, nil, 1024, 1024)
+				Expect(err).To(BeNil())
+				defer conn.Close()
+
+				Expect(conn.WriteMessage(websocket.BinaryMessage, make([]byte, 128))).To(Succeed())
+
+				_, _, err = conn.ReadMessage()
+				closed <- err
+			}))
+
+			defer server.Close()
+
+			client, err := pho.DialWithOptions(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), &pho.ClientOptions{
+				MaxMessageSize: 64,
+			})
+			Expect(err).To(BeNil())
+			defer client.Close()
+
+			var reason error
+			Eventually(closed).Should(Receive(&reason))
+			Expect(websocket.IsCloseError(reason, websocket.CloseMessageTooBig)).To(BeTrue())
+		})
+	})
+
+	Context("when reconnect is enabled", func() {
+		var (
+			router *pho.Mux
+			server *httptest.Server
+			client *pho.Client
+		)
+
+		BeforeEach(func() {
+			router = pho.NewMux()
+			server = httptest.NewServer(router)
+
+			var err error
+			client, err = pho.DialWithOptions(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), &pho.ClientOptions{
+				Reconnect: &pho.ReconnectPolicy{
+					MinDelay:    10 * time.Millisecond,
+					MaxDelay:    50 * time.Millisecond,
+					Jitter:      0.2,
+					MaxAttempts: 3,
+				},
+			})
+			Expect(err).To(BeNil())
+		})
+
+		AfterEach(func() {
+			client.Close()
+			router.Close()
+			server.Close()
+		})
+
+		It("restores the connection and keeps the handlers", func() {
+			router.On("hello", func(w pho.SocketWriter, r *pho.Request) {
+				Expect(w.Write("hello", http.StatusOK, r.Body)).To(Succeed())
+			})
+
+			disconnectCnt := 0
+			client.OnDisconnect(func(err error) {
+				disconnectCnt++
+			})
+
+			reconnectCnt := 0
+			client.OnReconnect(func(attempts int) {
+				reconnectCnt++
+			})
+
+			cnt := 0
+			client.On("hello", func(resp *pho.Response) {
+				cnt++
+			})
+
+			router.Close()
+			Expect(client.Write("bye", []byte(`"world"`))).To(Succeed())
+
+			Eventually(func() int { return disconnectCnt }).Should(Equal(1))
+			Eventually(func() int { return reconnectCnt }).Should(Equal(1))
+
+			Expect(client.Write("hello", []byte(`"world"`))).To(Succeed())
+			Eventually(func() int { return cnt }).Should(Equal(1))
+		})
+
+		Context("when the server is gone", func() {
+			It("gives up after max attempts", func() {
+				errCnt := 0
+				client.OnError(func(err error) {
+					errCnt++
+				})
+
+				reconnectCnt := 0
+				client.OnReconnect(func(attempts int) {
+					reconnectCnt++
+				})
+
+				server.Close()
+				router.Close()
+				Expect(client.Write("bye", []byte(`"world"`))).To(Succeed())
+
+				Eventually(func() int { return errCnt }).Should(BeNumerically(">=", 4))
+				Consistently(func() int { return reconnectCnt }).Should(Equal(0))
+			})
+		})
+	})
+
 	Context("when cannot connect to the server", func() {
 		It("returns the error", func() {
 			client, err := pho.Dial("ws://test.com", nil)
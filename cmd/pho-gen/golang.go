@@ -0,0 +1,206 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"go/types"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// goTemplate generates the verb constants, the typed client, the server
+// interface, the unimplemented server stub and the registration function
+var goTemplate = template.Must(template.New("go").Funcs(template.FuncMap{
+	"type":    func(m *Method, expr string) string { return goType(m, expr) },
+	"comment": comment,
+	"params":  goParams,
+	"verb":    goVerb,
+}).Parse(`// Code generated by pho-gen. DO NOT EDIT.
+
+package {{ .Package }}
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/svett/pho"
+{{- range .Imports }}
+	{{ if .Name }}{{ .Name }} {{ end }}"{{ .Path }}"
+{{- end }}
+)
+
+{{ $service := .Name -}}
+
+// The verbs of {{ $service }}
+const (
+{{- range .Methods }}
+	{{ $service }}{{ .Name }}Verb = "{{ .Verb }}"
+{{- end }}
+)
+{{ if .Interface }}
+{{ if .Doc }}{{ comment .Doc }}{{ else }}// {{ $service }} serves the verbs of the service{{ end }}
+type {{ $service }} interface {
+{{- range .Methods }}
+	{{ if .Doc }}{{ comment .Doc }}
+	{{ end -}}
+	{{ .Name }}(ctx context.Context, in {{ type . "request" }}) ({{ type . "response" }}, error)
+{{- end }}
+}
+{{ end }}
+var (
+{{- if not .HasParams }}
+	_ {{ $service }} = &{{ $service }}Client{}
+{{- end }}
+	_ {{ $service }} = &Unimplemented{{ $service }}{}
+)
+
+// {{ $service }}Client calls the verbs of {{ $service }} with a pho.Client
+type {{ $service }}Client struct {
+	client *pho.Client
+}
+
+// New{{ $service }}Client creates a client of {{ $service }}
+func New{{ $service }}Client(client *pho.Client) *{{ $service }}Client {
+	return &{{ $service }}Client{client: client}
+}
+{{ range .Methods }}
+// {{ .Name }} calls the "{{ .Verb }}" verb
+func (c *{{ $service }}Client) {{ .Name }}(ctx context.Context, {{ params . }}in {{ type . "request" }}) ({{ type . "response" }}, error) {
+	return pho.TypedCall[{{ type . "request" }}, {{ type . "response" }}](ctx, c.client, {{ verb $service . }}, in)
+}
+{{ end }}
+// Unimplemented{{ $service }} responds to every verb of {{ $service }} with
+// 501 Not Implemented. It can be embedded by the servers that implement a
+// part of the verbs.
+type Unimplemented{{ $service }} struct{}
+{{ range .Methods }}
+// {{ .Name }} responds to the "{{ .Verb }}" verb with 501 Not Implemented
+func (Unimplemented{{ $service }}) {{ .Name }}(ctx context.Context, in {{ type . "request" }}) ({{ type . "response" }}, error) {
+	var out {{ type . "response" }}
+	return out, &pho.ResponseError{
+		StatusCode: http.StatusNotImplemented,
+		Message:    {{ printf "The verb %q is not implemented" .Verb | printf "%q" }},
+	}
+}
+{{ end }}
+// Register{{ $service }} registers the handlers of the server on the router
+func Register{{ $service }}(router pho.Router, server {{ $service }}) {
+{{- range .Methods }}
+	router.Handle({{ $service }}{{ .Name }}Verb, pho.Handle(server.{{ .Name }}))
+{{- end }}
+}
+`))
+
+// goImports are imported by the generated Go code
+var goImports = map[string]bool{
+	"context":              true,
+	"net/http":             true,
+	"github.com/svett/pho": true,
+}
+
+// generateGo generates the Go client and server of the service
+func generateGo(service *Service) ([]byte, error) {
+	data := *service
+	data.Imports = nil
+
+	for _, imp := range service.Imports {
+		if !goImports[imp.Path] || imp.Name != "" {
+			data.Imports = append(data.Imports, imp)
+		}
+	}
+
+	buffer := &bytes.Buffer{}
+
+	if err := goTemplate.Execute(buffer, &data); err != nil {
+		return nil, err
+	}
+
+	source, err := format.Source(buffer.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("The generated Go code is invalid: %v", err)
+	}
+
+	return source, nil
+}
+
+// goType returns the Go type of the request or the response of the method
+func goType(method *Method, kind string) string {
+	if kind == "request" {
+		return types.ExprString(method.Request)
+	}
+	return types.ExprString(method.Response)
+}
+
+// goParams returns the parameters of the client method that fill in the
+// verb parameters (ex. "id string, ")
+func goParams(method *Method) string {
+	var params strings.Builder
+
+	for _, param := range method.Params() {
+		fmt.Fprintf(&params, "%s string, ", paramName(param))
+	}
+
+	return params.String()
+}
+
+// goVerb returns the Go expression of the verb called by the client method
+// (ex. "order:" + id + ":get")
+func goVerb(service string, method *Method) string {
+	if len(method.Params()) == 0 {
+		return service + method.Name + "Verb"
+	}
+
+	var (
+		parts   []string
+		literal string
+	)
+
+	for index, segment := range strings.Split(method.Verb, ":") {
+		if index > 0 {
+			literal += ":"
+		}
+
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if literal != "" {
+				parts = append(parts, strconv.Quote(literal))
+			}
+			parts = append(parts, paramName(segment[1:len(segment)-1]))
+			literal = ""
+			continue
+		}
+
+		literal += segment
+	}
+
+	if literal != "" {
+		parts = append(parts, strconv.Quote(literal))
+	}
+
+	return strings.Join(parts, " + ")
+}
+
+// paramName returns the name of the verb parameter in the generated code.
+// The names that are Go or TypeScript keywords or are used by the client
+// methods get a suffix.
+func paramName(param string) string {
+	switch {
+	case token.IsKeyword(param), tsKeywords[param], param == "ctx", param == "in", param == "c", param == "signal", param == "body":
+		return param + "Param"
+	default:
+		return param
+	}
+}
+
+// comment returns the text as a Go comment
+func comment(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+
+	for index, line := range lines {
+		lines[index] = strings.TrimRight("// "+line, " ")
+	}
+
+	return strings.Join(lines, "\n\t")
+}
@@ -0,0 +1,91 @@
+// Command pho-gen generates typed clients and server stubs of the verbs
+// described by a schema.
+//
+// The schema is a Go interface whose methods have the signature
+// func(context.Context, In) (Out, error). The verb of a method is set by a
+// "pho:verb" directive and defaults to the snake case of its name:
+//
+//	//go:generate pho-gen -schema service.go -type UserService -ts ../web/users.ts
+//
+//	type UserService interface {
+//		// pho:verb user:create
+//		CreateUser(ctx context.Context, in *CreateUserInput) (*User, error)
+//	}
+//
+// Or a YAML file that lists the verbs with their Go request and response
+// types:
+//
+//	package: users
+//	service: UserService
+//	imports:
+//	  - github.com/acme/models
+//	verbs:
+//	  - verb: user:create
+//	    method: CreateUser
+//	    request: "*CreateUserInput"
+//	    response: "*models.User"
+//
+// The generated Go file has the verb constants, a client that wraps
+// pho.Client, an Unimplemented stub and a function that registers a server
+// on a pho.Router. The optional TypeScript client speaks the JSON envelope
+// of pho.Request and pho.Response.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	var (
+		schema   = flag.String("schema", "", "the Go file or the YAML file that describes the verbs")
+		typeName = flag.String("type", "", "the interface of the Go schema")
+		output   = flag.String("o", "", "the generated Go file (defaults to <schema>_pho.go)")
+		ts       = flag.String("ts", "", "the generated TypeScript client (not generated by default)")
+	)
+
+	flag.Parse()
+
+	if err := run(*schema, *typeName, *output, *ts); err != nil {
+		fmt.Fprintf(os.Stderr, "pho-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(schema, typeName, output, ts string) error {
+	if schema == "" {
+		return fmt.Errorf("The schema is not provided")
+	}
+
+	service, err := loadService(schema, typeName)
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		output = strings.TrimSuffix(schema, filepath.Ext(schema)) + "_pho.go"
+	}
+
+	source, err := generateGo(service)
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(output, source, 0644); err != nil {
+		return err
+	}
+
+	if ts == "" {
+		return nil
+	}
+
+	client, err := generateTypeScript(service)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(ts, client, 0644)
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"flag"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+// update rewrites the golden files with the generated code
+var update = flag.Bool("update", false, "update the golden files")
+
+func TestPhoGen(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "PhoGen Suite")
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// expectGolden compares the generated file with its golden file
+func expectGolden(generated, golden string) {
+	data, err := os.ReadFile(generated)
+	Expect(err).To(BeNil())
+
+	if *update {
+		Expect(os.WriteFile(golden, data, 0644)).To(Succeed())
+	}
+
+	expected, err := os.ReadFile(golden)
+	Expect(err).To(BeNil())
+	Expect(string(data)).To(Equal(string(expected)))
+}
+
+// expectCompiles builds the schema package with the generated file. The
+// package is copied into the module, so that it can import pho.
+func expectCompiles(schemaDir, generated string) {
+	dir, err := os.MkdirTemp(".", "_build")
+	Expect(err).To(BeNil())
+	defer os.RemoveAll(dir)
+
+	files, err := filepath.Glob(filepath.Join(schemaDir, "*.go"))
+	Expect(err).To(BeNil())
+
+	for _, file := range append(files, generated) {
+		data, err := os.ReadFile(file)
+		Expect(err).To(BeNil())
+		Expect(os.WriteFile(filepath.Join(dir, filepath.Base(file)), data, 0644)).To(Succeed())
+	}
+
+	output, err := exec.Command("go", "vet", "./"+filepath.Base(dir)).CombinedOutput()
+	Expect(err).To(BeNil(), string(output))
+}
+
+var _ = Describe("pho-gen", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "pho-gen")
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	Context("when the schema is a Go interface", func() {
+		It("generates the Go code", func() {
+			output := filepath.Join(dir, "service_pho.go")
+
+			Expect(run("testdata/users/service.go", "UserService", output, "")).To(Succeed())
+			expectGolden(output, "testdata/users/service_pho.go.golden")
+			expectCompiles("testdata/users", output)
+		})
+
+		It("generates the TypeScript client", func() {
+			output := filepath.Join(dir, "service_pho.go")
+			ts := filepath.Join(dir, "users.ts")
+
+			Expect(run("testdata/users/service.go", "UserService", output, ts)).To(Succeed())
+			expectGolden(ts, "testdata/users/users.ts.golden")
+		})
+	})
+
+	Context("when the schema is a YAML file", func() {
+		It("generates the Go code", func() {
+			output := filepath.Join(dir, "service_pho.go")
+
+			Expect(run("testdata/orders/service.yaml", "", output, "")).To(Succeed())
+			expectGolden(output, "testdata/orders/service_pho.go.golden")
+			expectCompiles("testdata/orders", output)
+		})
+
+		It("generates the TypeScript client", func() {
+			output := filepath.Join(dir, "service_pho.go")
+			ts := filepath.Join(dir, "orders.ts")
+
+			Expect(run("testdata/orders/service.yaml", "", output, ts)).To(Succeed())
+			expectGolden(ts, "testdata/orders/orders.ts.golden")
+		})
+	})
+
+	Context("when the schema is invalid", func() {
+		schema := func(name, content string) string {
+			filename := filepath.Join(dir, name)
+			Expect(os.WriteFile(filename, []byte(content), 0644)).To(Succeed())
+			return filename
+		}
+
+		It("rejects the verbs with a wildcard", func() {
+			filename := schema("service.yaml", "service: DocumentService\nverbs:\n  - verb: document:*\n    method: Open\n")
+
+			Expect(run(filename, "", "", "")).To(MatchError(`The verb "document:*" of "Open" has a wildcard that cannot be called`))
+		})
+
+		It("rejects the parameters that are not identifiers", func() {
+			filename := schema("service.yaml", "service: DocumentService\nverbs:\n  - verb: document:{document-id}:open\n    method: Open\n")
+
+			Expect(run(filename, "", "", "")).To(MatchError(`The parameter "document-id" of the verb "document:{document-id}:open" is not a valid identifier`))
+		})
+
+		It("rejects the YAML schemas without a service", func() {
+			filename := schema("service.yaml", "verbs:\n  - verb: document:open\n")
+
+			Expect(run(filename, "", "", "")).To(MatchError(ContainSubstring("does not have a service")))
+		})
+
+		It("rejects the Go schemas without the interface", func() {
+			filename := schema("service.go", "package documents\n")
+
+			Expect(run(filename, "", "", "")).To(MatchError(ContainSubstring("The interface of the schema")))
+			Expect(run(filename, "DocumentService", "", "")).To(MatchError(ContainSubstring(`The interface "DocumentService" is not declared`)))
+		})
+
+		It("rejects the methods with another signature", func() {
+			filename := schema("service.go", "package documents\n\ntype DocumentService interface {\n\tOpen(id string) error\n}\n")
+
+			Expect(run(filename, "DocumentService", "", "")).To(MatchError(`The method "Open" must have the signature func(context.Context, In) (Out, error)`))
+		})
+
+		It("requires the schema", func() {
+			Expect(run("", "", "", "")).To(MatchError("The schema is not provided"))
+		})
+	})
+})
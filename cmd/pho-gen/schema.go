@@ -0,0 +1,516 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// verbDirective sets the verb of an interface method (ex. "pho:verb user:create")
+const verbDirective = "pho:verb"
+
+// Service is a set of verbs served by a router
+type Service struct {
+	// Package of the generated Go code
+	Package string
+	// Name of the service
+	Name string
+	// Doc describes the service
+	Doc string
+	// Imports are the packages of the request and response types
+	Imports []*Import
+	// Methods serve the verbs of the service
+	Methods []*Method
+	// Interface is true when the service interface has to be generated (the
+	// YAML schemas do not declare it in Go)
+	Interface bool
+	// Types are the types declared along the schema
+	Types map[string]ast.Expr
+}
+
+// Import is an imported package
+type Import struct {
+	Name string
+	Path string
+}
+
+// Method serves a verb
+type Method struct {
+	// Name of the method
+	Name string
+	// Verb of the requests
+	Verb string
+	// Doc describes the method
+	Doc string
+	// Request is the type of the request body
+	Request ast.Expr
+	// Response is the type of the response body
+	Response ast.Expr
+}
+
+// Params returns the parameters of the verb (ex. "id" for "order:{id}:get")
+func (m *Method) Params() []string {
+	var params []string
+
+	for _, segment := range strings.Split(m.Verb, ":") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			params = append(params, segment[1:len(segment)-1])
+		}
+	}
+
+	return params
+}
+
+// HasParams returns true if a verb of the service has parameters. The
+// client methods of such verbs take the parameters before the request body.
+func (s *Service) HasParams() bool {
+	for _, method := range s.Methods {
+		if len(method.Params()) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// yamlSchema is a YAML schema
+type yamlSchema struct {
+	Package string   `yaml:"package"`
+	Service string   `yaml:"service"`
+	Doc     string   `yaml:"doc"`
+	Imports []string `yaml:"imports"`
+	Verbs   []struct {
+		Verb     string `yaml:"verb"`
+		Method   string `yaml:"method"`
+		Doc      string `yaml:"doc"`
+		Request  string `yaml:"request"`
+		Response string `yaml:"response"`
+	} `yaml:"verbs"`
+}
+
+// loadService loads the service from a YAML schema (".yaml" or ".yml") or
+// from an interface declared in a Go file
+func loadService(filename, name string) (*Service, error) {
+	var (
+		service *Service
+		err     error
+	)
+
+	switch filepath.Ext(filename) {
+	case ".yaml", ".yml":
+		service, err = loadYAML(filename)
+	default:
+		service, err = loadInterface(filename, name)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if service.Types, err = loadTypes(filepath.Dir(filename)); err != nil {
+		return nil, err
+	}
+
+	for _, method := range service.Methods {
+		for _, segment := range strings.Split(method.Verb, ":") {
+			if segment == "*" {
+				return nil, fmt.Errorf("The verb %q of %q has a wildcard that cannot be called", method.Verb, method.Name)
+			}
+		}
+
+		for _, param := range method.Params() {
+			if !token.IsIdentifier(param) {
+				return nil, fmt.Errorf("The parameter %q of the verb %q is not a valid identifier", param, method.Verb)
+			}
+		}
+	}
+
+	service.Imports = usedImports(service)
+	return service, nil
+}
+
+// loadYAML loads the service from a YAML schema
+func loadYAML(filename string) (*Service, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	schema := &yamlSchema{}
+	if err := yaml.Unmarshal(data, schema); err != nil {
+		return nil, fmt.Errorf("The schema %q is malformed: %v", filename, err)
+	}
+
+	if schema.Service == "" {
+		return nil, fmt.Errorf("The schema %q does not have a service", filename)
+	}
+
+	service := &Service{
+		Package:   schema.Package,
+		Name:      schema.Service,
+		Doc:       schema.Doc,
+		Interface: true,
+	}
+
+	if service.Package == "" {
+		if service.Package, err = packageName(filepath.Dir(filename)); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, importPath := range schema.Imports {
+		imp := &Import{Path: importPath}
+
+		// an import can have a name (ex. "models github.com/acme/models")
+		if fields := strings.Fields(importPath); len(fields) == 2 {
+			imp = &Import{Name: fields[0], Path: fields[1]}
+		}
+
+		service.Imports = append(service.Imports, imp)
+	}
+
+	for _, verb := range schema.Verbs {
+		if verb.Verb == "" {
+			return nil, fmt.Errorf("The schema %q has a verb without a name", filename)
+		}
+
+		method := &Method{
+			Name: verb.Method,
+			Verb: verb.Verb,
+			Doc:  verb.Doc,
+		}
+
+		if method.Name == "" {
+			method.Name = exportedName(verb.Verb)
+		}
+
+		if method.Request, err = parseType(verb.Request); err != nil {
+			return nil, fmt.Errorf("The request type of %q is invalid: %v", verb.Verb, err)
+		}
+
+		if method.Response, err = parseType(verb.Response); err != nil {
+			return nil, fmt.Errorf("The response type of %q is invalid: %v", verb.Verb, err)
+		}
+
+		service.Methods = append(service.Methods, method)
+	}
+
+	return service, nil
+}
+
+// parseType parses a Go type expression. An empty expression is an empty
+// struct.
+func parseType(expr string) (ast.Expr, error) {
+	if strings.TrimSpace(expr) == "" {
+		expr = "struct{}"
+	}
+	return parser.ParseExpr(expr)
+}
+
+// loadInterface loads the service from the interface declared in the Go file.
+// Its methods must have the signature func(context.Context, In) (Out, error).
+func loadInterface(filename, name string) (*Service, error) {
+	if name == "" {
+		return nil, fmt.Errorf("The interface of the schema %q is not provided", filename)
+	}
+
+	file, err := parser.ParseFile(token.NewFileSet(), filename, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	service := &Service{Package: file.Name.Name, Name: name}
+
+	for _, imp := range file.Imports {
+		importPath, _ := strconv.Unquote(imp.Path.Value)
+
+		service.Imports = append(service.Imports, &Import{Path: importPath})
+		if imp.Name != nil {
+			service.Imports[len(service.Imports)-1].Name = imp.Name.Name
+		}
+	}
+
+	var iface *ast.InterfaceType
+
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+
+		for _, spec := range gen.Specs {
+			spec := spec.(*ast.TypeSpec)
+			if spec.Name.Name != name {
+				continue
+			}
+
+			if iface, ok = spec.Type.(*ast.InterfaceType); !ok {
+				return nil, fmt.Errorf("The type %q is not an interface", name)
+			}
+
+			service.Doc = docText(spec.Doc, gen.Doc)
+		}
+	}
+
+	if iface == nil {
+		return nil, fmt.Errorf("The interface %q is not declared in %q", name, filename)
+	}
+
+	for _, field := range iface.Methods.List {
+		fn, ok := field.Type.(*ast.FuncType)
+		if !ok || len(field.Names) == 0 {
+			return nil, fmt.Errorf("The interface %q embeds another interface", name)
+		}
+
+		method := &Method{Name: field.Names[0].Name}
+
+		params := fieldTypes(fn.Params)
+		results := fieldTypes(fn.Results)
+
+		if len(params) != 2 || !isIdent(params[0], "context", "Context") ||
+			len(results) != 2 || !isIdent(results[1], "", "error") {
+			return nil, fmt.Errorf("The method %q must have the signature func(context.Context, In) (Out, error)", method.Name)
+		}
+
+		method.Request = params[1]
+		method.Response = results[0]
+		method.Verb, method.Doc = verbOf(field.Doc, method.Name)
+
+		service.Methods = append(service.Methods, method)
+	}
+
+	return service, nil
+}
+
+// verbOf returns the verb set by the directive of the method and the rest
+// of its doc. The verb defaults to the snake case of the method name.
+func verbOf(group *ast.CommentGroup, name string) (string, string) {
+	verb := snakeName(name)
+
+	if group == nil {
+		return verb, ""
+	}
+
+	var lines []string
+
+	for _, comment := range group.List {
+		text := strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(comment.Text, "//"), " "))
+
+		if strings.HasPrefix(text, verbDirective) {
+			verb = strings.TrimSpace(strings.TrimPrefix(text, verbDirective))
+			continue
+		}
+
+		lines = append(lines, text)
+	}
+
+	return verb, strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+// docText returns the text of the first comment group
+func docText(groups ...*ast.CommentGroup) string {
+	for _, group := range groups {
+		if group != nil {
+			return strings.TrimSpace(group.Text())
+		}
+	}
+	return ""
+}
+
+// fieldTypes returns the type of every field in the list
+func fieldTypes(list *ast.FieldList) []ast.Expr {
+	var types []ast.Expr
+
+	if list == nil {
+		return types
+	}
+
+	for _, field := range list.List {
+		count := len(field.Names)
+		if count == 0 {
+			count = 1
+		}
+
+		for index := 0; index < count; index++ {
+			types = append(types, field.Type)
+		}
+	}
+
+	return types
+}
+
+// isIdent returns true if the expression is the identifier (ex. "error") or
+// the qualified identifier (ex. "context.Context")
+func isIdent(expr ast.Expr, pkg, name string) bool {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return pkg == "" && expr.Name == name
+	case *ast.SelectorExpr:
+		ident, ok := expr.X.(*ast.Ident)
+		return ok && ident.Name == pkg && expr.Sel.Name == name
+	default:
+		return false
+	}
+}
+
+// loadTypes loads the types declared in the Go files of the directory. They
+// describe the payloads of the TypeScript client.
+func loadTypes(dir string) (map[string]ast.Expr, error) {
+	types := map[string]ast.Expr{}
+
+	files, err := parseDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			if spec, ok := node.(*ast.TypeSpec); ok && spec.TypeParams == nil {
+				types[spec.Name.Name] = spec.Type
+			}
+			return true
+		})
+	}
+
+	return types, nil
+}
+
+// packageName returns the package of the Go files in the directory. It
+// defaults to the name of the directory.
+func packageName(dir string) (string, error) {
+	files, err := parseDir(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for _, file := range files {
+		return file.Name.Name, nil
+	}
+
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.ReplaceAll(filepath.Base(abs), "-", "_"), nil
+}
+
+// parseDir parses the Go files of the directory except the tests and the
+// generated files
+func parseDir(dir string) ([]*ast.File, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*ast.File
+
+	for _, match := range matches {
+		if strings.HasSuffix(match, "_test.go") {
+			continue
+		}
+
+		file, err := parser.ParseFile(token.NewFileSet(), match, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+
+		if ast.IsGenerated(file) {
+			continue
+		}
+
+		files = append(files, file)
+	}
+
+	return files, nil
+}
+
+// usedImports returns the imports referenced by the request and response
+// types
+func usedImports(service *Service) []*Import {
+	used := map[string]bool{}
+
+	for _, method := range service.Methods {
+		for _, expr := range []ast.Expr{method.Request, method.Response} {
+			ast.Inspect(expr, func(node ast.Node) bool {
+				if sel, ok := node.(*ast.SelectorExpr); ok {
+					if ident, ok := sel.X.(*ast.Ident); ok {
+						used[ident.Name] = true
+					}
+				}
+				return true
+			})
+		}
+	}
+
+	var imports []*Import
+
+	for _, imp := range service.Imports {
+		name := imp.Name
+		if name == "" {
+			name = path.Base(imp.Path)
+		}
+
+		if used[name] && name != "context" {
+			imports = append(imports, imp)
+		}
+	}
+
+	return imports
+}
+
+// exportedName returns the exported Go name of the verb
+// (ex. "UserCreate" for "user:create")
+func exportedName(verb string) string {
+	var name strings.Builder
+
+	upper := true
+
+	for _, r := range verb {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+
+		name.WriteRune(r)
+	}
+
+	return name.String()
+}
+
+// snakeName returns the snake case of the Go name
+// (ex. "create_user" for "CreateUser")
+func snakeName(name string) string {
+	var snake strings.Builder
+
+	runes := []rune(name)
+
+	for index, r := range runes {
+		if unicode.IsUpper(r) {
+			// the words start after a lower case letter or before a lower case
+			// letter that follows an acronym (ex. "get_http_status")
+			if index > 0 && (unicode.IsLower(runes[index-1]) ||
+				(index+1 < len(runes) && unicode.IsLower(runes[index+1]) && unicode.IsUpper(runes[index-1]))) {
+				snake.WriteRune('_')
+			}
+			r = unicode.ToLower(r)
+		}
+
+		snake.WriteRune(r)
+	}
+
+	return snake.String()
+}
@@ -0,0 +1,13 @@
+package orders
+
+// Order is an order of items
+type Order struct {
+	ID    string `json:"id,omitempty"`
+	Items []Item `json:"items"`
+}
+
+// Item is an ordered item
+type Item struct {
+	SKU      string `json:"sku"`
+	Quantity int    `json:"quantity"`
+}
@@ -0,0 +1,34 @@
+package users
+
+import (
+	"context"
+	"time"
+)
+
+// User is a registered user
+type User struct {
+	ID        string            `json:"id"`
+	Name      string            `json:"name"`
+	Tags      []string          `json:"tags,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Manager   *User             `json:"manager,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// CreateUserInput creates a user
+type CreateUserInput struct {
+	Name string `json:"name"`
+}
+
+// UserService manages the users
+type UserService interface {
+	// CreateUser creates a user
+	//
+	// pho:verb user:create
+	CreateUser(ctx context.Context, in *CreateUserInput) (*User, error)
+
+	// pho:verb user:{id}:get
+	GetUser(ctx context.Context, in struct{}) (*User, error)
+
+	ListUsers(ctx context.Context, in struct{}) ([]*User, error)
+}
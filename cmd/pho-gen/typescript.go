@@ -0,0 +1,471 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+// tsTemplate generates the TypeScript client. It speaks the JSON envelope of
+// pho.Request and pho.Response.
+var tsTemplate = template.Must(template.New("ts").Funcs(template.FuncMap{
+	"method": lowerName,
+	"quote":  strconv.Quote,
+	"join":   strings.Join,
+	"params": tsParams,
+	"verb":   tsVerb,
+}).Parse(`// Code generated by pho-gen. DO NOT EDIT.
+
+/** Request is the envelope of the requests sent to the server */
+export interface Request<T = unknown> {
+  id?: string;
+  type: string;
+  header?: Record<string, string>;
+  body?: T;
+}
+
+/** Response is the envelope of the responses sent by the server */
+export interface Response<T = unknown> {
+  id?: string;
+  type: string;
+  status_code?: number;
+  header?: Record<string, string>;
+  payload?: T;
+}
+
+/** FieldError describes an invalid field of the request body */
+export interface FieldError {
+  field: string;
+  message: string;
+}
+
+/** ResponseError is an error response sent by the server */
+export class ResponseError extends Error {
+  constructor(
+    readonly statusCode: number,
+    message: string,
+    readonly header: Record<string, string> = {},
+    readonly fields: FieldError[] = [],
+  ) {
+    super(message);
+    this.name = "ResponseError";
+  }
+}
+{{ range .Interfaces }}
+export interface {{ .Name }}{{ if .Extends }} extends {{ join .Extends ", " }}{{ end }} {
+{{- range .Fields }}
+  {{ .Name }}{{ if .Optional }}?{{ end }}: {{ .Type }};
+{{- end }}
+}
+{{ end }}
+{{- $service := .Service.Name }}
+/** The verbs of {{ $service }} */
+export const {{ $service }}Verbs = {
+{{- range .Methods }}
+  {{ .Name }}: {{ quote .Verb }},
+{{- end }}
+} as const;
+
+interface {{ $service }}Call {
+  resolve: (response: Response) => void;
+  reject: (error: Error) => void;
+}
+
+/** {{ $service }}Client calls the verbs of {{ $service }} */
+export class {{ $service }}Client {
+  /** onResponse receives the responses that do not answer a call */
+  onResponse?: (response: Response) => void;
+
+  private readonly socket: WebSocket;
+  private readonly opened: Promise<void>;
+  private readonly calls = new Map<string, {{ $service }}Call>();
+  private sequence = 0;
+
+  constructor(socket: string | WebSocket) {
+    this.socket = typeof socket === "string" ? new WebSocket(socket, "json") : socket;
+    this.socket.binaryType = "arraybuffer";
+
+    this.opened = new Promise((resolve, reject) => {
+      if (this.socket.readyState === WebSocket.OPEN) {
+        resolve();
+        return;
+      }
+
+      this.socket.addEventListener("open", () => resolve(), { once: true });
+      this.socket.addEventListener("error", () => reject(new Error("The socket cannot be opened")), { once: true });
+    });
+
+    this.socket.addEventListener("message", (event) => this.receive(event.data));
+    this.socket.addEventListener("close", () => this.fail(new Error("The socket is closed")));
+  }
+{{ range .Methods }}
+  /** {{ method .Name }} calls the {{ quote .Verb }} verb{{ if .Doc }}. {{ .Doc }}{{ end }} */
+  {{ method .Name }}({{ params .Method }}body: {{ .Request }}, signal?: AbortSignal): Promise<{{ .Response }}> {
+    return this.call<{{ .Request }}, {{ .Response }}>({{ verb $service .Method }}, body, signal);
+  }
+{{ end }}
+  /** call sends the request and resolves the payload of its response */
+  async call<In, Out>(verb: string, body: In, signal?: AbortSignal): Promise<Out> {
+    await this.opened;
+
+    const id = String(++this.sequence);
+
+    return new Promise<Out>((resolve, reject) => {
+      if (signal?.aborted) {
+        reject(signal.reason ?? new Error("The call is aborted"));
+        return;
+      }
+
+      const abort = () => {
+        this.calls.delete(id);
+        this.send({ id, type: "cancel" });
+        reject(signal?.reason ?? new Error("The call is aborted"));
+      };
+
+      signal?.addEventListener("abort", abort, { once: true });
+
+      this.calls.set(id, {
+        resolve: (response) => {
+          signal?.removeEventListener("abort", abort);
+          resolve(response.payload as Out);
+        },
+        reject: (error) => {
+          signal?.removeEventListener("abort", abort);
+          reject(error);
+        },
+      });
+
+      this.send({ id, type: verb, body });
+    });
+  }
+
+  /** close closes the socket and rejects the pending calls */
+  close(): void {
+    this.socket.close();
+  }
+
+  private send(request: Request): void {
+    this.socket.send(JSON.stringify(request));
+  }
+
+  private receive(data: string | ArrayBuffer): void {
+    const text = typeof data === "string" ? data : new TextDecoder().decode(data);
+    const response = JSON.parse(text) as Response;
+
+    const call = response.id ? this.calls.get(response.id) : undefined;
+    if (!call) {
+      this.onResponse?.(response);
+      return;
+    }
+
+    this.calls.delete(response.id as string);
+
+    if (response.type === "error") {
+      const payload = (response.payload ?? {}) as { error?: string; fields?: FieldError[] };
+      call.reject(new ResponseError(response.status_code ?? 0, payload.error ?? "", response.header, payload.fields));
+      return;
+    }
+
+    call.resolve(response);
+  }
+
+  private fail(error: Error): void {
+    for (const call of this.calls.values()) {
+      call.reject(error);
+    }
+    this.calls.clear();
+  }
+}
+`))
+
+// tsKeywords are the reserved words of TypeScript that are not Go keywords
+var tsKeywords = map[string]bool{
+	"await": true, "catch": true, "class": true, "delete": true, "do": true,
+	"enum": true, "export": true, "extends": true, "false": true, "finally": true,
+	"function": true, "in": true, "instanceof": true, "let": true, "new": true,
+	"null": true, "super": true, "this": true, "throw": true, "true": true,
+	"try": true, "typeof": true, "var": true, "void": true, "while": true,
+	"with": true, "yield": true,
+}
+
+// tsInterface is a TypeScript interface of a Go struct
+type tsInterface struct {
+	Name    string
+	Extends []string
+	Fields  []*tsField
+}
+
+// tsField is a property of a TypeScript interface
+type tsField struct {
+	Name     string
+	Type     string
+	Optional bool
+}
+
+// tsMethod is a method of the TypeScript client
+type tsMethod struct {
+	Method   *Method
+	Name     string
+	Verb     string
+	Doc      string
+	Request  string
+	Response string
+}
+
+// tsGenerator maps the Go types to TypeScript types
+type tsGenerator struct {
+	service    *Service
+	interfaces map[string]*tsInterface
+	visiting   map[string]bool
+}
+
+// generateTypeScript generates the TypeScript client of the service
+func generateTypeScript(service *Service) ([]byte, error) {
+	g := &tsGenerator{
+		service:    service,
+		interfaces: map[string]*tsInterface{},
+		visiting:   map[string]bool{},
+	}
+
+	var methods []*tsMethod
+
+	for _, method := range service.Methods {
+		methods = append(methods, &tsMethod{
+			Method:   method,
+			Name:     method.Name,
+			Verb:     method.Verb,
+			Doc:      strings.Join(strings.Fields(method.Doc), " "),
+			Request:  g.typeOf(method.Request),
+			Response: g.typeOf(method.Response),
+		})
+	}
+
+	names := make([]string, 0, len(g.interfaces))
+	for name := range g.interfaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	interfaces := make([]*tsInterface, 0, len(names))
+	for _, name := range names {
+		interfaces = append(interfaces, g.interfaces[name])
+	}
+
+	buffer := &bytes.Buffer{}
+
+	err := tsTemplate.Execute(buffer, map[string]interface{}{
+		"Service":    service,
+		"Methods":    methods,
+		"Interfaces": interfaces,
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("The TypeScript client cannot be generated: %v", err)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// typeOf returns the TypeScript type of the Go type. The types that cannot
+// be mapped are unknown.
+func (g *tsGenerator) typeOf(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return g.typeOf(expr.X)
+	case *ast.ParenExpr:
+		return g.typeOf(expr.X)
+	case *ast.Ident:
+		return g.identType(expr.Name)
+	case *ast.SelectorExpr:
+		if isIdent(expr, "time", "Time") {
+			return "string"
+		}
+		return "unknown"
+	case *ast.ArrayType:
+		if ident, ok := expr.Elt.(*ast.Ident); ok && (ident.Name == "byte" || ident.Name == "uint8") {
+			// encoding/json encodes the bytes as a base64 string
+			return "string"
+		}
+
+		elem := g.typeOf(expr.Elt)
+		if strings.ContainsAny(elem, " |") {
+			elem = "(" + elem + ")"
+		}
+
+		return elem + "[]"
+	case *ast.MapType:
+		return "Record<string, " + g.typeOf(expr.Value) + ">"
+	case *ast.StructType:
+		var props []string
+		for _, field := range g.fields(expr, nil) {
+			optional := ""
+			if field.Optional {
+				optional = "?"
+			}
+			props = append(props, fmt.Sprintf("%s%s: %s", field.Name, optional, field.Type))
+		}
+
+		if len(props) == 0 {
+			return "Record<string, never>"
+		}
+
+		return "{ " + strings.Join(props, "; ") + " }"
+	default:
+		return "unknown"
+	}
+}
+
+// identType returns the TypeScript type of the Go identifier
+func (g *tsGenerator) identType(name string) string {
+	switch name {
+	case "string", "error":
+		return "string"
+	case "bool":
+		return "boolean"
+	case "int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64", "uintptr",
+		"float32", "float64", "byte", "rune":
+		return "number"
+	}
+
+	expr, ok := g.service.Types[name]
+	if !ok || g.visiting[name] {
+		return "unknown"
+	}
+
+	st, ok := expr.(*ast.StructType)
+	if !ok {
+		// the named types are replaced by their underlying types
+		g.visiting[name] = true
+		defer delete(g.visiting, name)
+		return g.typeOf(expr)
+	}
+
+	if _, ok := g.interfaces[name]; !ok {
+		// the placeholder allows the struct to reference itself
+		iface := &tsInterface{Name: name}
+		g.interfaces[name] = iface
+		iface.Fields = g.fields(st, &iface.Extends)
+	}
+
+	return name
+}
+
+// fields returns the properties of the struct. The embedded structs are
+// added to extends.
+func (g *tsGenerator) fields(st *ast.StructType, extends *[]string) []*tsField {
+	var fields []*tsField
+
+	for _, field := range st.Fields.List {
+		tag := ""
+		if field.Tag != nil {
+			value, _ := strconv.Unquote(field.Tag.Value)
+			tag = reflect.StructTag(value).Get("json")
+		}
+
+		options := strings.Split(tag, ",")
+		name := options[0]
+
+		if name == "-" && len(options) == 1 {
+			continue
+		}
+
+		idents := field.Names
+
+		if len(idents) == 0 {
+			if name == "" {
+				if embedded := g.typeOf(field.Type); embedded != "unknown" && extends != nil {
+					*extends = append(*extends, embedded)
+				}
+				continue
+			}
+
+			// the embedded field is named by its tag
+			idents = []*ast.Ident{ast.NewIdent(name)}
+		}
+
+		for _, ident := range idents {
+			if !ast.IsExported(ident.Name) && len(field.Names) > 0 {
+				continue
+			}
+
+			property := &tsField{Name: name, Type: g.typeOf(field.Type)}
+			if property.Name == "" {
+				property.Name = ident.Name
+			}
+
+			for _, option := range options[1:] {
+				switch option {
+				case "omitempty", "omitzero":
+					property.Optional = true
+				case "string":
+					property.Type = "string"
+				}
+			}
+
+			fields = append(fields, property)
+		}
+	}
+
+	return fields
+}
+
+// tsParams returns the parameters of the client method that fill in the
+// verb parameters (ex. "id: string, ")
+func tsParams(method *Method) string {
+	var params strings.Builder
+
+	for _, param := range method.Params() {
+		fmt.Fprintf(&params, "%s: string, ", paramName(param))
+	}
+
+	return params.String()
+}
+
+// tsVerb returns the TypeScript expression of the verb called by the client
+// method (ex. `order:${id}:get`)
+func tsVerb(service string, method *Method) string {
+	if len(method.Params()) == 0 {
+		return service + "Verbs." + method.Name
+	}
+
+	var parts []string
+
+	for _, segment := range strings.Split(method.Verb, ":") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			segment = "${" + paramName(segment[1:len(segment)-1]) + "}"
+		} else {
+			segment = strings.NewReplacer("\\", "\\\\", "`", "\\`", "$", "\\$").Replace(segment)
+		}
+		parts = append(parts, segment)
+	}
+
+	return "`" + strings.Join(parts, ":") + "`"
+}
+
+// lowerName returns the Go name with a lower case first word
+// (ex. "httpStatus" for "HTTPStatus")
+func lowerName(name string) string {
+	runes := []rune(name)
+
+	for index := range runes {
+		if !unicode.IsUpper(runes[index]) {
+			break
+		}
+
+		// the last upper case letter of an acronym starts the next word
+		if index > 0 && index+1 < len(runes) && unicode.IsLower(runes[index+1]) {
+			break
+		}
+
+		runes[index] = unicode.ToLower(runes[index])
+	}
+
+	return string(runes)
+}
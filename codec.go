@@ -0,0 +1,187 @@
+package pho
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+var (
+	// JSONCodec encodes the messages as JSON
+	JSONCodec Codec = &jsonCodec{}
+
+	// MsgPackCodec encodes the messages as MessagePack
+	MsgPackCodec Codec = &msgpackCodec{}
+)
+
+var codecs = struct {
+	rw       sync.RWMutex
+	registry map[string]Codec
+}{
+	registry: map[string]Codec{
+		JSONCodec.Name():    JSONCodec,
+		MsgPackCodec.Name(): MsgPackCodec,
+	},
+}
+
+// A Codec encodes and decodes the messages of a connection. The codec is
+// negotiated by its name through the Sec-WebSocket-Protocol header.
+type Codec interface {
+	// Name of the codec used as a WebSocket subprotocol
+	Name() string
+	// Marshal returns the encoding of v
+	Marshal(v interface{}) ([]byte, error)
+	// Unmarshal decodes the data and stores the result in v
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// RegisterCodec makes a codec available to all servers and clients
+func RegisterCodec(codec Codec) {
+	codecs.rw.Lock()
+	defer codecs.rw.Unlock()
+	codecs.registry[codec.Name()] = codec
+}
+
+// LookupCodec returns the codec registered with the provided name
+func LookupCodec(name string) (Codec, bool) {
+	codecs.rw.RLock()
+	defer codecs.rw.RUnlock()
+	codec, ok := codecs.registry[name]
+	return codec, ok
+}
+
+// SocketCodec returns the codec used by the socket
+func SocketCodec(w SocketWriter) Codec {
+	if codec, ok := w.Metadata()[MetadataCodecKey].(Codec); ok {
+		return codec
+	}
+	return JSONCodec
+}
+
+type jsonCodec struct{}
+
+func (c *jsonCodec) Name() string {
+	return "json"
+}
+
+func (c *jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (c *jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+// msgpackNil is the MessagePack encoding of nil
+const msgpackNil = 0xc0
+
+// msgpackRaw is a raw MessagePack value. The bodies of the messages are
+// embedded as is instead of as binary strings.
+type msgpackRaw []byte
+
+// MarshalMsgpack returns m as the MessagePack encoding of m.
+func (m msgpackRaw) MarshalMsgpack() ([]byte, error) {
+	if len(m) == 0 {
+		return []byte{msgpackNil}, nil
+	}
+	return m, nil
+}
+
+// UnmarshalMsgpack sets *m to a copy of data.
+func (m *msgpackRaw) UnmarshalMsgpack(data []byte) error {
+	if m == nil {
+		return errors.New("pho: UnmarshalMsgpack on nil pointer")
+	}
+	*m = append((*m)[0:0], data...)
+	return nil
+}
+
+// msgpackRequest is the MessagePack encoding of a Request
+type msgpackRequest struct {
+	ID     string     `json:"id,omitempty"`
+	Type   string     `json:"Type,omitempty"`
+	Header Header     `json:"header,omitempty"`
+	Body   msgpackRaw `json:"body"`
+}
+
+// msgpackResponse is the MessagePack encoding of a Response
+type msgpackResponse struct {
+	ID         string     `json:"id,omitempty"`
+	Type       string     `json:"type,omitempty"`
+	StatusCode int        `json:"status_code,omitempty"`
+	Header     Header     `json:"header,omitempty"`
+	Payload    msgpackRaw `json:"payload"`
+}
+
+type msgpackCodec struct{}
+
+func (c *msgpackCodec) Name() string {
+	return "msgpack"
+}
+
+func (c *msgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	switch message := v.(type) {
+	case *Request:
+		v = &msgpackRequest{
+			ID:     message.ID,
+			Type:   message.Type,
+			Header: message.Header,
+			Body:   msgpackRaw(message.Body),
+		}
+	case *Response:
+		v = &msgpackResponse{
+			ID:         message.ID,
+			Type:       message.Type,
+			StatusCode: message.StatusCode,
+			Header:     message.Header,
+			Payload:    msgpackRaw(message.Payload),
+		}
+	}
+
+	buffer := &bytes.Buffer{}
+
+	enc := msgpack.NewEncoder(buffer)
+	enc.SetCustomStructTag("json")
+
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func (c *msgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(data))
+	dec.SetCustomStructTag("json")
+
+	switch message := v.(type) {
+	case *Request:
+		envelope := &msgpackRequest{}
+		if err := dec.Decode(envelope); err != nil {
+			return err
+		}
+
+		message.ID = envelope.ID
+		message.Type = envelope.Type
+		message.Header = envelope.Header
+		message.Body = json.RawMessage(envelope.Body)
+		return nil
+	case *Response:
+		envelope := &msgpackResponse{}
+		if err := dec.Decode(envelope); err != nil {
+			return err
+		}
+
+		message.ID = envelope.ID
+		message.Type = envelope.Type
+		message.StatusCode = envelope.StatusCode
+		message.Header = envelope.Header
+		message.Payload = json.RawMessage(envelope.Payload)
+		return nil
+	}
+
+	return dec.Decode(v)
+}
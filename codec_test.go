@@ -0,0 +1,128 @@
+package pho_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/svett/pho"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type message struct {
+	Text string `json:"text"`
+}
+
+type upperCodec struct {
+	pho.Codec
+}
+
+func (c *upperCodec) Name() string {
+	return "upper"
+}
+
+var _ = Describe("Codec", func() {
+	var (
+		router *pho.Mux
+		server *httptest.Server
+		url    string
+	)
+
+	BeforeEach(func() {
+		router = pho.NewMux()
+		server = httptest.NewServer(router)
+		url = fmt.Sprintf("ws://%s", server.Listener.Addr().String())
+
+		router.On("echo", func(w pho.SocketWriter, r *pho.Request) {
+			defer GinkgoRecover()
+
+			msg := &message{}
+			Expect(pho.SocketCodec(w).Unmarshal(r.Body, msg)).To(Succeed())
+
+			data, err := pho.SocketCodec(w).Marshal(msg)
+			Expect(err).To(BeNil())
+			Expect(w.Write("echo", http.StatusOK, data)).To(Succeed())
+		})
+
+		router.On("fail", func(w pho.SocketWriter, r *pho.Request) {
+			w.WriteError(fmt.Errorf("oh no!"), http.StatusBadRequest)
+		})
+	})
+
+	AfterEach(func() {
+		router.Close()
+		server.Close()
+	})
+
+	It("marshals and unmarshals raw messages", func() {
+		for _, codec := range []pho.Codec{pho.JSONCodec, pho.MsgPackCodec} {
+			body, err := codec.Marshal(&message{Text: "hello"})
+			Expect(err).To(BeNil())
+
+			data, err := codec.Marshal(&pho.Request{ID: "1", Type: "echo", Body: body})
+			Expect(err).To(BeNil())
+
+			request := &pho.Request{}
+			Expect(codec.Unmarshal(data, request)).To(Succeed())
+			Expect(request.ID).To(Equal("1"))
+			Expect(request.Type).To(Equal("echo"))
+
+			msg := &message{}
+			Expect(codec.Unmarshal(request.Body, msg)).To(Succeed())
+			Expect(msg.Text).To(Equal("hello"))
+		}
+	})
+
+	It("embeds the body in the MessagePack envelope", func() {
+		body, err := pho.MsgPackCodec.Marshal(&message{Text: "hello"})
+		Expect(err).To(BeNil())
+
+		data, err := pho.MsgPackCodec.Marshal(&pho.Request{Type: "echo", Body: body})
+		Expect(err).To(BeNil())
+
+		envelope := map[string]interface{}{}
+		Expect(pho.MsgPackCodec.Unmarshal(data, &envelope)).To(Succeed())
+		Expect(envelope["body"]).To(Equal(map[string]interface{}{"text": "hello"}))
+	})
+
+	It("uses the codec requested by the client", func() {
+		client, err := pho.DialWithOptions(url, &pho.ClientOptions{Codec: pho.MsgPackCodec})
+		Expect(err).To(BeNil())
+		defer client.Close()
+
+		body, err := pho.MsgPackCodec.Marshal(&message{Text: "hello"})
+		Expect(err).To(BeNil())
+
+		response, err := client.Call(context.Background(), "echo", body)
+		Expect(err).To(BeNil())
+
+		msg := &message{}
+		Expect(pho.MsgPackCodec.Unmarshal(response.Payload, msg)).To(Succeed())
+		Expect(msg.Text).To(Equal("hello"))
+
+		_, err = client.Call(context.Background(), "fail", nil)
+		Expect(err).To(MatchError("oh no!"))
+	})
+
+	Context("when the codec is registered", func() {
+		It("can be negotiated", func() {
+			codec := &upperCodec{Codec: pho.JSONCodec}
+			pho.RegisterCodec(codec)
+
+			registered, ok := pho.LookupCodec("upper")
+			Expect(ok).To(BeTrue())
+			Expect(registered).To(Equal(codec))
+
+			client, err := pho.DialWithOptions(url, &pho.ClientOptions{Codec: codec})
+			Expect(err).To(BeNil())
+			defer client.Close()
+
+			response, err := client.Call(context.Background(), "echo", []byte(`{"text":"hello"}`))
+			Expect(err).To(BeNil())
+			Expect(string(response.Payload)).To(Equal(`{"text":"hello"}`))
+		})
+	})
+})
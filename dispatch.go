@@ -0,0 +1,185 @@
+package pho
+
+import (
+	"context"
+	"strings"
+)
+
+// socketCtxKey is the context key of the socket that sent the request
+var socketCtxKey = &contextKey{"Socket"}
+
+// WorkerPool limits the number of handlers running concurrently across all
+// sockets
+type WorkerPool struct {
+	slots chan struct{}
+}
+
+// NewWorkerPool creates a pool that runs up to size handlers at a time
+func NewWorkerPool(size int) *WorkerPool {
+	return &WorkerPool{slots: make(chan struct{}, size)}
+}
+
+func (p *WorkerPool) acquire() {
+	if p != nil {
+		p.slots <- struct{}{}
+	}
+}
+
+func (p *WorkerPool) release() {
+	if p != nil {
+		<-p.slots
+	}
+}
+
+// OrderedFunc reports whether the requests of the verb must be handled in
+// the order of arrival
+type OrderedFunc func(verb string) bool
+
+// orderedVerbs returns an OrderedFunc that matches the provided verbs
+func orderedVerbs(verbs []string) OrderedFunc {
+	if len(verbs) == 0 {
+		return nil
+	}
+
+	set := map[string]bool{}
+	for _, verb := range verbs {
+		set[strings.ToLower(verb)] = true
+	}
+
+	return func(verb string) bool {
+		return set[strings.ToLower(verb)]
+	}
+}
+
+// dispatch hands the request over to the serial worker or, when the socket
+// allows concurrent requests, serves it in a new goroutine. It blocks the
+// read loop while the serial worker is busy or the socket has as many
+// requests in flight as its concurrency limit, so a client cannot queue an
+// unbounded number of requests. Meanwhile the cancel messages, the pongs and
+// the close frame of the client are not read, but the requests in flight
+// are still cancelled when the connection is closed.
+func (c *Socket) dispatch(request *Request) {
+	request, header, release := c.track(request)
+
+	if c.slots == nil {
+		w := &replyWriter{Socket: c, id: request.ID, header: header, metadata: c.metadata}
+
+		serve := func() {
+			defer c.handlers.Done()
+			defer release()
+
+			c.serveRPCFn(w, request)
+		}
+
+		c.handlers.Add(1)
+
+		// the connection is closed while the worker is busy
+		select {
+		case c.serial <- serve:
+		case <-c.closeChan:
+			c.handlers.Done()
+			release()
+		}
+		return
+	}
+
+	// the concurrent handlers must not share the metadata map
+	metadata := Metadata{}
+	for key, value := range c.metadata {
+		metadata[key] = value
+	}
+
+	w := &replyWriter{Socket: c, id: request.ID, header: header, metadata: metadata}
+
+	var prev chan struct{}
+	done := make(chan struct{})
+
+	if c.ordered != nil && c.ordered(request.Type) {
+		prev, c.last = c.last, done
+	}
+
+	select {
+	case c.slots <- struct{}{}:
+	case <-c.closeChan:
+		release()
+		close(done)
+		return
+	}
+
+	c.handlers.Add(1)
+
+	go func() {
+		defer c.handlers.Done()
+		defer func() { <-c.slots }()
+		defer close(done)
+		defer release()
+
+		if prev != nil {
+			<-prev
+		}
+
+		c.workers.acquire()
+		defer c.workers.release()
+
+		c.serveRPCFn(w, request)
+	}()
+}
+
+// serveSerial serves the requests of the socket one by one in the order of
+// arrival until the read loop stops
+func (c *Socket) serveSerial() {
+	for serve := range c.serial {
+		serve()
+	}
+}
+
+// stopSerial stops the serial worker once it has served the requests that
+// have been dispatched
+func (c *Socket) stopSerial() {
+	if c.serial != nil {
+		close(c.serial)
+	}
+}
+
+// track derives the request context from the socket context, so that the
+// request can be cancelled by its ID. It returns the header of the responses
+// and the function that releases the context once the request is served.
+func (c *Socket) track(request *Request) (*Request, Header, func()) {
+	header := Header{}
+
+	ctx, cancel := context.WithCancel(c.ctx)
+	ctx = context.WithValue(ctx, responseHeaderCtxKey, header)
+	ctx = context.WithValue(ctx, socketCtxKey, c)
+	request = request.WithContext(ctx)
+
+	if request.ID == "" {
+		return request, header, cancel
+	}
+
+	c.pendingMu.Lock()
+	c.pending[request.ID] = &cancel
+	c.pendingMu.Unlock()
+
+	release := func() {
+		cancel()
+
+		c.pendingMu.Lock()
+		if c.pending[request.ID] == &cancel {
+			delete(c.pending, request.ID)
+		}
+		c.pendingMu.Unlock()
+	}
+
+	return request, header, release
+}
+
+// cancelRequest cancels the context of the request being served
+func (c *Socket) cancelRequest(id string) {
+	c.pendingMu.Lock()
+	cancel, ok := c.pending[id]
+	c.pendingMu.Unlock()
+
+	if ok {
+		(*cancel)()
+	}
+}
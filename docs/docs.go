@@ -0,0 +1,389 @@
+// Package docs generates an AsyncAPI 3.0 document of the verbs served by a
+// pho router.
+//
+// The payloads are described by the handlers created with pho.Handle and by
+// the verb options:
+//
+//	mux.Handle("user:create", pho.Handle(createUser))
+//	mux.On("user:delete", deleteUser)
+//
+//	doc, err := docs.Generate(mux, &docs.Options{
+//		Title:   "Users",
+//		Version: "1.0.0",
+//		Verbs: map[string]*docs.VerbOptions{
+//			"user:delete": {Request: DeleteUserInput{}, ErrorCodes: []int{404}},
+//		},
+//	})
+//
+//	data, err := doc.YAML()
+//
+// Every verb is a channel whose address is the verb. The server receives the
+// requests of the channel and replies on the channel of the response verb.
+package docs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+
+	"github.com/svett/pho"
+)
+
+// AsyncAPIVersion is the version of the AsyncAPI specification
+const AsyncAPIVersion = "3.0.0"
+
+// The names of the messages of the channels
+const (
+	RequestMessage  = "request"
+	ResponseMessage = "response"
+	ErrorMessage    = "error"
+)
+
+// ErrorComponent is the name of the message and schema of the error
+// responses
+const ErrorComponent = "SocketError"
+
+// Document is an AsyncAPI document
+type Document struct {
+	AsyncAPI   string                `json:"asyncapi"`
+	Info       Info                  `json:"info"`
+	Servers    map[string]*Server    `json:"servers,omitempty"`
+	Channels   map[string]*Channel   `json:"channels"`
+	Operations map[string]*Operation `json:"operations"`
+	Components Components            `json:"components"`
+}
+
+// Info describes the API
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// Server describes a server that serves the API
+type Server struct {
+	// Host of the server (ex. "example.com:8080")
+	Host string `json:"host"`
+	// Protocol of the server (ex. "ws" or "wss")
+	Protocol string `json:"protocol"`
+	// Pathname of the mux (ex. "/ws")
+	Pathname    string `json:"pathname,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Channel describes the messages sent with a verb
+type Channel struct {
+	Address    string                `json:"address"`
+	Messages   map[string]*Message   `json:"messages"`
+	Parameters map[string]*Parameter `json:"parameters,omitempty"`
+}
+
+// Parameter describes a parameter of the channel address (ex. "{id}")
+type Parameter struct {
+	Description string `json:"description,omitempty"`
+}
+
+// Message describes a message payload. Ref references a message of the
+// components.
+type Message struct {
+	Ref         string  `json:"$ref,omitempty"`
+	Name        string  `json:"name,omitempty"`
+	ContentType string  `json:"contentType,omitempty"`
+	Payload     *Schema `json:"payload,omitempty"`
+}
+
+// Operation describes the requests of a verb and the replies to them
+type Operation struct {
+	Action   string       `json:"action"`
+	Channel  *Reference   `json:"channel"`
+	Summary  string       `json:"summary,omitempty"`
+	Messages []*Reference `json:"messages"`
+	Reply    *Reply       `json:"reply,omitempty"`
+	// ErrorCodes are the status codes of the error responses
+	ErrorCodes []int `json:"x-pho-error-codes,omitempty"`
+}
+
+// Reply describes the responses to the requests of an operation
+type Reply struct {
+	Channel  *Reference   `json:"channel"`
+	Messages []*Reference `json:"messages"`
+}
+
+// Reference references an object of the document
+type Reference struct {
+	Ref string `json:"$ref"`
+}
+
+// Components are the objects referenced by the document
+type Components struct {
+	Schemas  map[string]*Schema  `json:"schemas,omitempty"`
+	Messages map[string]*Message `json:"messages,omitempty"`
+}
+
+// Options provides the document options
+type Options struct {
+	// Title of the API (defaults to "pho")
+	Title string
+	// Version of the API (defaults to "1.0.0")
+	Version string
+	// Description of the API
+	Description string
+	// Servers that serve the API
+	Servers map[string]*Server
+	// Verbs describe the verbs by their full verb (ex. "user:{id}:update").
+	// They override the description provided by the handlers.
+	Verbs map[string]*VerbOptions
+}
+
+// VerbOptions describes a verb
+type VerbOptions struct {
+	// Summary of the verb
+	Summary string
+	// Request is a value of the type of the request body
+	Request interface{}
+	// Response is a value of the type of the response body
+	Response interface{}
+	// ResponseVerb is the verb of the responses (defaults to the verb, which
+	// the handlers created by pho.Handle reply under)
+	ResponseVerb string
+	// ErrorCodes are the status codes of the error responses (the handlers
+	// created by pho.Handle default to 400 and 500)
+	ErrorCodes []int
+}
+
+// Generate generates the document of the verbs served by the router and
+// its mounted routers
+func Generate(router pho.Routes, options *Options) (*Document, error) {
+	if options == nil {
+		options = &Options{}
+	}
+
+	g := &generator{
+		options: options,
+		schemas: newSchemas(),
+		doc: &Document{
+			AsyncAPI: AsyncAPIVersion,
+			Info: Info{
+				Title:       options.Title,
+				Version:     options.Version,
+				Description: options.Description,
+			},
+			Servers:    options.Servers,
+			Channels:   map[string]*Channel{},
+			Operations: map[string]*Operation{},
+		},
+	}
+
+	if g.doc.Info.Title == "" {
+		g.doc.Info.Title = "pho"
+	}
+
+	if g.doc.Info.Version == "" {
+		g.doc.Info.Version = "1.0.0"
+	}
+
+	if err := pho.Walk(router, g.operation); err != nil {
+		return nil, err
+	}
+
+	g.doc.Components.Schemas = g.schemas.components
+	return g.doc, nil
+}
+
+// JSON returns the document encoded as JSON
+func (d *Document) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// YAML returns the document encoded as YAML
+func (d *Document) YAML() ([]byte, error) {
+	data, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+
+	// JSON is YAML, so the document is decoded as a node to keep the order
+	// of the keys and encoded in the block style
+	node := &yaml.Node{}
+	if err := yaml.Unmarshal(data, node); err != nil {
+		return nil, err
+	}
+
+	blockStyle(node)
+
+	buffer := &bytes.Buffer{}
+
+	encoder := yaml.NewEncoder(buffer)
+	encoder.SetIndent(2)
+
+	if err := encoder.Encode(node); err != nil {
+		return nil, err
+	}
+
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// blockStyle clears the flow and quoted styles of the node and its children
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// generator builds the document
+type generator struct {
+	options *Options
+	schemas *schemas
+	doc     *Document
+}
+
+// operation adds the operation of the verb and its channels
+func (g *generator) operation(verb string, handler pho.Handler, middlewares []pho.MiddlewareFunc) error {
+	var (
+		request    reflect.Type
+		response   reflect.Type
+		reply      bool
+		errorCodes []int
+	)
+
+	if describer, ok := handler.(pho.TypeDescriber); ok {
+		request = describer.RequestType()
+		response = describer.ResponseType()
+		reply = true
+		errorCodes = []int{http.StatusBadRequest, http.StatusInternalServerError}
+	}
+
+	options := g.options.Verbs[verb]
+	if options == nil {
+		options = &VerbOptions{}
+	}
+
+	if options.Request != nil {
+		request = reflect.TypeOf(options.Request)
+	}
+
+	if options.Response != nil {
+		response = reflect.TypeOf(options.Response)
+		reply = true
+	}
+
+	if len(options.ErrorCodes) > 0 {
+		errorCodes = options.ErrorCodes
+		reply = true
+	}
+
+	responseVerb := verb
+	if options.ResponseVerb != "" {
+		responseVerb = options.ResponseVerb
+		reply = true
+	}
+
+	id, channel := g.channel(verb)
+	channel.Messages[RequestMessage] = &Message{
+		Name:    verb,
+		Payload: g.schemas.schemaOf(request),
+	}
+
+	operation := &Operation{
+		Action:     "receive",
+		Channel:    &Reference{Ref: "#/channels/" + id},
+		Summary:    options.Summary,
+		Messages:   []*Reference{{Ref: "#/channels/" + id + "/messages/" + RequestMessage}},
+		ErrorCodes: errorCodes,
+	}
+
+	if reply {
+		replyID, replyChannel := g.channel(responseVerb)
+		replyChannel.Messages[ResponseMessage] = &Message{
+			Name:    responseVerb,
+			Payload: g.schemas.schemaOf(response),
+		}
+
+		operation.Reply = &Reply{
+			Channel:  &Reference{Ref: "#/channels/" + replyID},
+			Messages: []*Reference{{Ref: "#/channels/" + replyID + "/messages/" + ResponseMessage}},
+		}
+
+		if len(errorCodes) > 0 {
+			replyChannel.Messages[ErrorMessage] = g.errorMessage()
+			operation.Reply.Messages = append(operation.Reply.Messages, &Reference{
+				Ref: "#/channels/" + replyID + "/messages/" + ErrorMessage,
+			})
+		}
+	}
+
+	g.doc.Operations[id] = operation
+	return nil
+}
+
+// channel returns the channel of the verb and its ID
+func (g *generator) channel(verb string) (string, *Channel) {
+	id := channelID(verb)
+
+	for index := 2; ; index++ {
+		channel, ok := g.doc.Channels[id]
+		if !ok {
+			break
+		}
+
+		if channel.Address == verb {
+			return id, channel
+		}
+
+		// another verb has the same ID (ex. "user:{id}" and "user_id")
+		id = fmt.Sprintf("%s_%d", channelID(verb), index)
+	}
+
+	channel := &Channel{Address: verb, Messages: map[string]*Message{}}
+
+	for _, segment := range strings.Split(verb, ":") {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if channel.Parameters == nil {
+				channel.Parameters = map[string]*Parameter{}
+			}
+			channel.Parameters[segment[1:len(segment)-1]] = &Parameter{}
+		}
+	}
+
+	g.doc.Channels[id] = channel
+	return id, channel
+}
+
+// errorMessage registers the error message and returns a reference to it
+func (g *generator) errorMessage() *Message {
+	if g.doc.Components.Messages == nil {
+		g.doc.Components.Messages = map[string]*Message{
+			ErrorComponent: {
+				Name:    pho.ErrorType,
+				Payload: g.schemas.schemaOf(reflect.TypeOf(pho.SocketError{})),
+			},
+		}
+	}
+
+	return &Message{Ref: "#/components/messages/" + ErrorComponent}
+}
+
+// invalidID matches the characters that are not allowed in the channel IDs
+var invalidID = regexp.MustCompile(`[^A-Za-z0-9_\-]+`)
+
+// channelID returns the ID of the channel of the verb. The IDs consist of
+// letters, digits, "_" and "-" (ex. "user_id_update" for "user:{id}:update").
+func channelID(verb string) string {
+	id := strings.Trim(invalidID.ReplaceAllString(verb, "_"), "_")
+	if id == "" {
+		return "all"
+	}
+	return id
+}
@@ -0,0 +1,258 @@
+package docs_test
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+
+	"github.com/svett/pho"
+	"github.com/svett/pho/docs"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type Audit struct {
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy string    `json:"created_by"`
+}
+
+type User struct {
+	Audit
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Tags    []string `json:"tags,omitempty"`
+	Manager *User    `json:"manager,omitempty"`
+	secret  string
+}
+
+type CreateUserInput struct {
+	Name  string `json:"name" validate:"required,min=3"`
+	Email string `json:"email" validate:"omitempty,email"`
+	Age   int    `json:"age,string"`
+}
+
+type DeleteUserInput struct {
+	Reason string `json:"reason" validate:"required"`
+}
+
+type Item struct {
+	Label string `json:"label"`
+}
+
+func createUser(ctx context.Context, in *CreateUserInput) (*User, error) {
+	return &User{Name: in.Name}, nil
+}
+
+func getOrg(ctx context.Context, in struct{}) (map[string]int, error) {
+	return map[string]int{}, nil
+}
+
+var _ = Describe("Generate", func() {
+	var (
+		router  *pho.Mux
+		options *docs.Options
+	)
+
+	BeforeEach(func() {
+		router = pho.NewMux()
+		router.Handle("user:create", pho.Handle(createUser))
+		router.On("user:{id}:delete", func(w pho.SocketWriter, r *pho.Request) {})
+		router.Route("org", func(r pho.Router) {
+			r.Handle("{id}:get", pho.Handle(getOrg))
+		})
+
+		options = &docs.Options{
+			Title:   "Users",
+			Version: "2.0.0",
+			Servers: map[string]*docs.Server{
+				"production": {Host: "example.com", Protocol: "wss", Pathname: "/ws"},
+			},
+			Verbs: map[string]*docs.VerbOptions{
+				"user:{id}:delete": {
+					Summary:    "Deletes a user",
+					Request:    DeleteUserInput{},
+					ErrorCodes: []int{404},
+				},
+			},
+		}
+	})
+
+	AfterEach(func() {
+		router.Close()
+	})
+
+	generate := func() *docs.Document {
+		doc, err := docs.Generate(router, options)
+		Expect(err).To(BeNil())
+		return doc
+	}
+
+	It("describes the API", func() {
+		doc := generate()
+		Expect(doc.AsyncAPI).To(Equal(docs.AsyncAPIVersion))
+		Expect(doc.Info).To(Equal(docs.Info{Title: "Users", Version: "2.0.0"}))
+		Expect(doc.Servers).To(HaveKey("production"))
+	})
+
+	It("describes the typed routes", func() {
+		doc := generate()
+
+		Expect(doc.Channels).To(HaveKey("user_create"))
+		channel := doc.Channels["user_create"]
+		Expect(channel.Address).To(Equal("user:create"))
+		Expect(channel.Messages[docs.RequestMessage].Payload).To(Equal(&docs.Schema{Ref: "#/components/schemas/CreateUserInput"}))
+		Expect(channel.Messages[docs.ResponseMessage].Payload).To(Equal(&docs.Schema{Ref: "#/components/schemas/User"}))
+		Expect(channel.Messages[docs.ErrorMessage]).To(Equal(&docs.Message{Ref: "#/components/messages/SocketError"}))
+
+		operation := doc.Operations["user_create"]
+		Expect(operation.Action).To(Equal("receive"))
+		Expect(operation.Channel).To(Equal(&docs.Reference{Ref: "#/channels/user_create"}))
+		Expect(operation.ErrorCodes).To(Equal([]int{400, 500}))
+		Expect(operation.Reply.Messages).To(Equal([]*docs.Reference{
+			{Ref: "#/channels/user_create/messages/response"},
+			{Ref: "#/channels/user_create/messages/error"},
+		}))
+	})
+
+	It("describes the pattern routes with the verb options", func() {
+		doc := generate()
+
+		channel := doc.Channels["user_id_delete"]
+		Expect(channel.Address).To(Equal("user:{id}:delete"))
+		Expect(channel.Parameters).To(Equal(map[string]*docs.Parameter{"id": {}}))
+		Expect(channel.Messages[docs.RequestMessage].Payload).To(Equal(&docs.Schema{Ref: "#/components/schemas/DeleteUserInput"}))
+
+		operation := doc.Operations["user_id_delete"]
+		Expect(operation.Summary).To(Equal("Deletes a user"))
+		Expect(operation.ErrorCodes).To(Equal([]int{404}))
+	})
+
+	It("does not reply to the untyped routes without options", func() {
+		delete(options.Verbs, "user:{id}:delete")
+
+		doc := generate()
+		Expect(doc.Operations["user_id_delete"].Reply).To(BeNil())
+		Expect(doc.Channels["user_id_delete"].Messages[docs.RequestMessage].Payload).To(Equal(&docs.Schema{}))
+	})
+
+	It("replies to the mounted routes on the channel of the full verb", func() {
+		doc := generate()
+
+		channel := doc.Channels["org_id_get"]
+		Expect(channel.Address).To(Equal("org:{id}:get"))
+		Expect(channel.Messages[docs.ResponseMessage].Name).To(Equal("org:{id}:get"))
+		Expect(channel.Messages[docs.ResponseMessage].Payload).To(Equal(&docs.Schema{
+			Type:                 "object",
+			AdditionalProperties: &docs.Schema{Type: "integer"},
+		}))
+
+		Expect(doc.Operations["org_id_get"].Reply.Channel).To(Equal(&docs.Reference{Ref: "#/channels/org_id_get"}))
+	})
+
+	It("replies on the channel of the response verb", func() {
+		options.Verbs["user:create"] = &docs.VerbOptions{ResponseVerb: "user:created"}
+
+		doc := generate()
+		Expect(doc.Channels["user_created"].Address).To(Equal("user:created"))
+		Expect(doc.Channels["user_created"].Messages).To(HaveKey(docs.ResponseMessage))
+		Expect(doc.Channels["user_create"].Messages).NotTo(HaveKey(docs.ResponseMessage))
+		Expect(doc.Operations["user_create"].Reply.Channel).To(Equal(&docs.Reference{Ref: "#/channels/user_created"}))
+	})
+
+	It("references the components of the named structs", func() {
+		doc := generate()
+
+		Expect(doc.Components.Schemas["User"]).To(Equal(&docs.Schema{
+			Type: "object",
+			Properties: map[string]*docs.Schema{
+				"id":         {Type: "string"},
+				"name":       {Type: "string"},
+				"tags":       {Type: "array", Items: &docs.Schema{Type: "string"}},
+				"manager":    {Ref: "#/components/schemas/User"},
+				"created_at": {Type: "string", Format: "date-time"},
+				"created_by": {Type: "string"},
+			},
+		}))
+
+		Expect(doc.Components.Schemas).To(HaveKey(docs.ErrorComponent))
+		Expect(doc.Components.Messages).To(HaveKey(docs.ErrorComponent))
+	})
+
+	It("requires the fields validated as required", func() {
+		doc := generate()
+
+		Expect(doc.Components.Schemas["CreateUserInput"]).To(Equal(&docs.Schema{
+			Type: "object",
+			Properties: map[string]*docs.Schema{
+				"name":  {Type: "string"},
+				"email": {Type: "string"},
+				"age":   {Type: "string"},
+			},
+			Required: []string{"name"},
+		}))
+		Expect(doc.Components.Schemas["DeleteUserInput"].Required).To(Equal([]string{"reason"}))
+	})
+
+	It("makes the colliding channel IDs unique", func() {
+		router.On("user_id", func(w pho.SocketWriter, r *pho.Request) {})
+		router.On("user:{id}", func(w pho.SocketWriter, r *pho.Request) {})
+
+		doc := generate()
+		Expect(doc.Channels["user_id"].Address).To(Equal("user_id"))
+		Expect(doc.Channels["user_id_2"].Address).To(Equal("user:{id}"))
+		Expect(doc.Operations["user_id_2"].Channel).To(Equal(&docs.Reference{Ref: "#/channels/user_id_2"}))
+	})
+
+	It("qualifies the colliding component names", func() {
+		router.On("item:get", func(w pho.SocketWriter, r *pho.Request) {})
+		options.Verbs["item:get"] = &docs.VerbOptions{Request: Item{}}
+
+		// a type of the same name and package
+		type Item struct {
+			Count int `json:"count"`
+		}
+
+		options.Verbs["user:{id}:delete"].Request = Item{}
+
+		doc := generate()
+		Expect(doc.Components.Schemas["Item"].Properties).To(HaveKey("label"))
+		Expect(doc.Components.Schemas["docs_test.Item"].Properties).To(HaveKey("count"))
+		Expect(doc.Channels["user_id_delete"].Messages[docs.RequestMessage].Payload).To(Equal(&docs.Schema{
+			Ref: "#/components/schemas/docs_test.Item",
+		}))
+	})
+
+	It("encodes the document as JSON", func() {
+		data, err := generate().JSON()
+		Expect(err).To(BeNil())
+
+		document := map[string]interface{}{}
+		Expect(json.Unmarshal(data, &document)).To(Succeed())
+		Expect(document).To(HaveKeyWithValue("asyncapi", "3.0.0"))
+		Expect(document).To(HaveKey("channels"))
+		Expect(document).To(HaveKey("operations"))
+		Expect(string(data)).To(ContainSubstring(`"$ref": "#/components/schemas/User"`))
+	})
+
+	It("encodes the document as YAML", func() {
+		doc := generate()
+
+		data, err := doc.YAML()
+		Expect(err).To(BeNil())
+		Expect(string(data)).To(HavePrefix("asyncapi: 3.0.0\n"))
+		Expect(string(data)).To(ContainSubstring("$ref: '#/components/schemas/User'"))
+
+		jsonData, err := doc.JSON()
+		Expect(err).To(BeNil())
+
+		fromYAML := map[string]interface{}{}
+		Expect(yaml.Unmarshal(data, &fromYAML)).To(Succeed())
+
+		fromJSON := map[string]interface{}{}
+		Expect(yaml.Unmarshal(jsonData, &fromJSON)).To(Succeed())
+		Expect(fromYAML).To(Equal(fromJSON))
+	})
+})
@@ -0,0 +1,210 @@
+package docs
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON Schema of a message payload
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// invalidName matches the characters that are not allowed in the names of
+// the components
+var invalidName = regexp.MustCompile(`[^A-Za-z0-9_.\-]+`)
+
+// schemas builds the schemas of the Go types. The named struct types are
+// registered as components and referenced by the other schemas.
+type schemas struct {
+	components map[string]*Schema
+	names      map[reflect.Type]string
+}
+
+func newSchemas() *schemas {
+	return &schemas{
+		components: map[string]*Schema{},
+		names:      map[reflect.Type]string{},
+	}
+}
+
+// schemaOf returns the schema of the type. A nil type accepts any value.
+func (s *schemas) schemaOf(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		return &Schema{}
+	case implements(t, jsonMarshalerType):
+		// the shape of the custom encoding is unknown
+		return &Schema{}
+	case implements(t, textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: s.schemaOf(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: s.schemaOf(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + s.component(t)}
+	default:
+		return &Schema{}
+	}
+}
+
+// component registers the schema of the named type and returns its name
+func (s *schemas) component(t reflect.Type) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+
+	name := s.nameOf(t)
+
+	// the placeholder allows the type to reference itself
+	schema := &Schema{}
+	s.names[t] = name
+	s.components[name] = schema
+
+	*schema = *s.object(t)
+	return name
+}
+
+// nameOf returns a free name of the component. The name of the type is
+// qualified by its package when it is taken by another type.
+func (s *schemas) nameOf(t reflect.Type) string {
+	name := invalidName.ReplaceAllString(t.Name(), "_")
+	if _, ok := s.components[name]; !ok {
+		return name
+	}
+
+	name = invalidName.ReplaceAllString(path.Base(t.PkgPath()), "_") + "." + name
+	if _, ok := s.components[name]; !ok {
+		return name
+	}
+
+	for index := 2; ; index++ {
+		candidate := fmt.Sprintf("%s%d", name, index)
+		if _, ok := s.components[candidate]; !ok {
+			return candidate
+		}
+	}
+}
+
+// object returns the schema of the struct type. The properties are named
+// after their json tags and the fields tagged `validate:"required"` are
+// required.
+func (s *schemas) object(t reflect.Type) *Schema {
+	schema := &Schema{Type: "object", Properties: map[string]*Schema{}}
+	s.fields(t, schema)
+	return schema
+}
+
+// fields adds the fields of the struct type to the schema. The fields of
+// the embedded structs are promoted unless they are shadowed.
+func (s *schemas) fields(t reflect.Type, schema *Schema) {
+	var embedded []reflect.Type
+
+	for index := 0; index < t.NumField(); index++ {
+		field := t.Field(index)
+
+		tag := strings.Split(field.Tag.Get("json"), ",")
+		name := tag[0]
+
+		if name == "-" && len(tag) == 1 {
+			continue
+		}
+
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+
+			// the promoted fields are added after the fields of this struct
+			if ft.Kind() == reflect.Struct {
+				embedded = append(embedded, ft)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		if _, ok := schema.Properties[name]; ok {
+			continue
+		}
+
+		property := s.schemaOf(field.Type)
+		for _, option := range tag[1:] {
+			if option == "string" {
+				property = &Schema{Type: "string"}
+			}
+		}
+
+		schema.Properties[name] = property
+
+		for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+			if rule == "required" {
+				schema.Required = append(schema.Required, name)
+			}
+		}
+	}
+
+	for _, ft := range embedded {
+		s.fields(ft, schema)
+	}
+}
+
+// implements returns true if the type or its pointer implements the interface
+func implements(t, iface reflect.Type) bool {
+	return t.Implements(iface) || reflect.PointerTo(t).Implements(iface)
+}
@@ -0,0 +1,165 @@
+package pho
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+)
+
+var (
+	responseVerbCtxKey   = &contextKey{"ResponseVerb"}
+	responseStatusCtxKey = &contextKey{"ResponseStatus"}
+)
+
+// StatusError is implemented by the errors that provide the status code of
+// their error response
+type StatusError interface {
+	error
+	// Status returns the status code of the error response
+	Status() int
+}
+
+// WithResponseVerb returns a copy of ctx with the verb of the responses
+func WithResponseVerb(ctx context.Context, verb string) context.Context {
+	return context.WithValue(ctx, responseVerbCtxKey, verb)
+}
+
+// ResponseVerb returns the verb of the responses to the request. It defaults
+// to the verb of the request.
+func ResponseVerb(r *Request) string {
+	if verb, ok := r.Context().Value(responseVerbCtxKey).(string); ok {
+		return verb
+	}
+	return r.Type
+}
+
+// WithResponseStatus returns a copy of ctx with the status of the responses
+func WithResponseStatus(ctx context.Context, status int) context.Context {
+	return context.WithValue(ctx, responseStatusCtxKey, status)
+}
+
+// ResponseStatus returns the status of the responses to the request. It
+// defaults to 200 OK.
+func ResponseStatus(r *Request) int {
+	if status, ok := r.Context().Value(responseStatusCtxKey).(int); ok {
+		return status
+	}
+	return http.StatusOK
+}
+
+// TypeDescriber is implemented by the handlers that describe the types of
+// their request and response bodies (ex. to generate their documentation)
+type TypeDescriber interface {
+	// RequestType returns the type of the request body
+	RequestType() reflect.Type
+	// ResponseType returns the type of the response body
+	ResponseType() reflect.Type
+}
+
+// Handle adapts a typed function to a Handler. The request body is decoded
+// into In with the codec of the socket. The result is written under the verb
+// set by WithResponseVerb or, unlike render.Respond, under the full verb sent
+// by the client, so that the responses of the mounted routes match their
+// requests. The status code of the error response is provided by StatusError
+// or *ResponseError, otherwise it is 500 Internal Server Error. The handler
+// implements TypeDescriber, so its types are available to Walk when it is
+// registered with Mux.Handle.
+func Handle[In, Out any](fn func(ctx context.Context, in In) (Out, error)) Handler {
+	return &typedHandler[In, Out]{fn: fn}
+}
+
+// typedHandler serves the requests with a typed function
+type typedHandler[In, Out any] struct {
+	fn func(ctx context.Context, in In) (Out, error)
+}
+
+// RequestType returns the type of the request body
+func (h *typedHandler[In, Out]) RequestType() reflect.Type {
+	return reflect.TypeOf((*In)(nil)).Elem()
+}
+
+// ResponseType returns the type of the response body
+func (h *typedHandler[In, Out]) ResponseType() reflect.Type {
+	return reflect.TypeOf((*Out)(nil)).Elem()
+}
+
+// ServeRPC decodes the request body, calls the function and writes its
+// result or error
+func (h *typedHandler[In, Out]) ServeRPC(w SocketWriter, r *Request) {
+	codec := SocketCodec(w)
+
+	var in In
+
+	if len(r.Body) > 0 {
+		if err := codec.Unmarshal(r.Body, &in); err != nil {
+			w.WriteError(fmt.Errorf("The request body is malformed: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+
+	out, err := h.fn(r.Context(), in)
+	if err != nil {
+		w.WriteError(err, errorStatus(err))
+		return
+	}
+
+	data, err := codec.Marshal(out)
+	if err != nil {
+		w.WriteError(err, http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(typedVerb(r), ResponseStatus(r), data)
+}
+
+// typedVerb returns the verb of the typed responses
+func typedVerb(r *Request) string {
+	if _, ok := r.Context().Value(responseVerbCtxKey).(string); ok {
+		return ResponseVerb(r)
+	}
+
+	if rctx := GetRouteContext(r.Context()); rctx != nil && rctx.Verb != "" {
+		return rctx.Verb
+	}
+
+	return r.Type
+}
+
+// errorStatus returns the status code of the error response
+func errorStatus(err error) int {
+	var serr StatusError
+	if errors.As(err, &serr) {
+		return serr.Status()
+	}
+
+	var rerr *ResponseError
+	if errors.As(err, &rerr) {
+		return rerr.StatusCode
+	}
+
+	return http.StatusInternalServerError
+}
+
+// TypedCall sends in as the body of an RPC request and decodes the response
+// payload into Out. It is the typed variant of Client.Call.
+func TypedCall[In, Out any](ctx context.Context, c *Client, verb string, in In) (Out, error) {
+	var out Out
+
+	body, err := c.codec.Marshal(in)
+	if err != nil {
+		return out, err
+	}
+
+	response, err := c.Call(ctx, verb, body)
+	if err != nil {
+		return out, err
+	}
+
+	if err := c.codec.Unmarshal(response.Payload, &out); err != nil {
+		return out, err
+	}
+
+	return out, nil
+}
@@ -0,0 +1,179 @@
+package pho_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+
+	"github.com/svett/pho"
+	"github.com/svett/pho/render"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type greeting struct {
+	Name string `json:"name"`
+}
+
+type reply struct {
+	Message string `json:"message"`
+}
+
+type statusError struct {
+	status int
+}
+
+func (e *statusError) Error() string {
+	return http.StatusText(e.status)
+}
+
+func (e *statusError) Status() int {
+	return e.status
+}
+
+var _ = Describe("Handle", func() {
+	var (
+		router *pho.Mux
+		server *httptest.Server
+		client *pho.Client
+	)
+
+	BeforeEach(func() {
+		router = pho.NewMux()
+		server = httptest.NewServer(router)
+
+		router.Handle("greet", pho.Handle(func(ctx context.Context, in greeting) (*reply, error) {
+			switch in.Name {
+			case "":
+				return nil, &statusError{status: http.StatusUnprocessableEntity}
+			case "jack":
+				return nil, fmt.Errorf("jack is not welcome")
+			default:
+				return &reply{Message: "Hello " + in.Name}, nil
+			}
+		}))
+
+		var err error
+		client, err = pho.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		client.Close()
+		router.Close()
+		server.Close()
+	})
+
+	It("decodes the request and encodes the response", func() {
+		response, err := client.Call(context.Background(), "greet", []byte(`{"name":"john"}`))
+		Expect(err).To(BeNil())
+		Expect(response.Type).To(Equal("greet"))
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+		Expect(string(response.Payload)).To(Equal(`{"message":"Hello john"}`))
+	})
+
+	It("writes the response under the verb set by render.Verb", func() {
+		router.Route("v2", func(r pho.Router) {
+			r.Use(func(next pho.Handler) pho.Handler {
+				return pho.HandlerFunc(func(w pho.SocketWriter, r *pho.Request) {
+					render.Verb(r, "greeted")
+					render.Status(r, http.StatusCreated)
+					next.ServeRPC(w, r)
+				})
+			})
+
+			r.Handle("greet", pho.Handle(func(ctx context.Context, in greeting) (reply, error) {
+				return reply{Message: "Hi " + in.Name}, nil
+			}))
+		})
+
+		response, err := client.Call(context.Background(), "v2:greet", []byte(`{"name":"john"}`))
+		Expect(err).To(BeNil())
+		Expect(response.Type).To(Equal("greeted"))
+		Expect(response.StatusCode).To(Equal(http.StatusCreated))
+	})
+
+	It("responds under the verb sent by the client to a nested route", func() {
+		router.Route("org", func(r pho.Router) {
+			r.Route("user", func(r pho.Router) {
+				r.Handle("{id}:greet", pho.Handle(func(ctx context.Context, in greeting) (reply, error) {
+					return reply{Message: "Hi " + in.Name}, nil
+				}))
+			})
+		})
+
+		response, err := client.Call(context.Background(), "org:user:42:greet", []byte(`{"name":"john"}`))
+		Expect(err).To(BeNil())
+		Expect(response.Type).To(Equal("org:user:42:greet"))
+		Expect(string(response.Payload)).To(Equal(`{"message":"Hi john"}`))
+	})
+
+	It("keeps the verb of the request for render.Respond in a nested route", func() {
+		router.Route("org", func(r pho.Router) {
+			r.On("greet", func(w pho.SocketWriter, r *pho.Request) {
+				render.Respond(w, r, reply{Message: "Hi"})
+			})
+		})
+
+		response, err := client.Call(context.Background(), "org:greet", []byte(`{}`))
+		Expect(err).To(BeNil())
+		Expect(response.Type).To(Equal("greet"))
+	})
+
+	It("maps the errors to status codes", func() {
+		_, err := client.Call(context.Background(), "greet", []byte(`{}`))
+		Expect(err).To(Equal(&pho.ResponseError{
+			StatusCode: http.StatusUnprocessableEntity,
+			Message:    "Unprocessable Entity",
+		}))
+
+		_, err = client.Call(context.Background(), "greet", []byte(`{"name":"jack"}`))
+		Expect(err).To(Equal(&pho.ResponseError{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "jack is not welcome",
+		}))
+
+		_, err = client.Call(context.Background(), "greet", []byte(`[]`))
+		Expect(err).To(HaveOccurred())
+		Expect(err.(*pho.ResponseError).StatusCode).To(Equal(http.StatusBadRequest))
+	})
+
+	It("describes the request and response types", func() {
+		router.With().Handle("welcome", pho.Handle(func(ctx context.Context, in greeting) (*reply, error) {
+			return &reply{Message: "Welcome " + in.Name}, nil
+		}))
+
+		err := pho.Walk(router, func(verb string, handler pho.Handler, middlewares []pho.MiddlewareFunc) error {
+			if verb != "welcome" {
+				return nil
+			}
+
+			describer, ok := handler.(pho.TypeDescriber)
+			Expect(ok).To(BeTrue())
+			Expect(describer.RequestType()).To(Equal(reflect.TypeOf(greeting{})))
+			Expect(describer.ResponseType()).To(Equal(reflect.TypeOf(&reply{})))
+			return nil
+		})
+
+		Expect(err).To(BeNil())
+	})
+
+	Describe("TypedCall", func() {
+		It("encodes the request and decodes the response", func() {
+			out, err := pho.TypedCall[greeting, reply](context.Background(), client, "greet", greeting{Name: "john"})
+			Expect(err).To(BeNil())
+			Expect(out).To(Equal(reply{Message: "Hello john"}))
+		})
+
+		It("returns the error response", func() {
+			_, err := pho.TypedCall[greeting, reply](context.Background(), client, "greet", greeting{})
+			Expect(err).To(Equal(&pho.ResponseError{
+				StatusCode: http.StatusUnprocessableEntity,
+				Message:    "Unprocessable Entity",
+			}))
+		})
+	})
+})
@@ -0,0 +1,98 @@
+package pho
+
+import (
+	"errors"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+var (
+	// ErrHeartbeatTimeout is reported when the peer does not answer a ping
+	// in time
+	ErrHeartbeatTimeout = errors.New("The heartbeat timed out")
+
+	// ErrServerClosed is reported when the server closes the connection
+	ErrServerClosed = errors.New("The server is closed")
+)
+
+// heartbeat keeps a connection alive and detects dead peers
+type heartbeat struct {
+	conn         *websocket.Conn
+	readDeadline time.Duration
+	pongTimeout  time.Duration
+	waiting      int32
+}
+
+// newHeartbeat installs ping and pong handlers on the connection
+func newHeartbeat(conn *websocket.Conn, readDeadline, pongTimeout time.Duration) *heartbeat {
+	if readDeadline <= 0 {
+		readDeadline = ReadDeadline
+	}
+
+	if pongTimeout <= 0 {
+		pongTimeout = PongTimeout
+	}
+
+	h := &heartbeat{
+		conn:         conn,
+		readDeadline: readDeadline,
+		pongTimeout:  pongTimeout,
+	}
+
+	conn.SetPingHandler(h.pong)
+	conn.SetPongHandler(func(string) error {
+		atomic.StoreInt32(&h.waiting, 0)
+		return h.extend()
+	})
+
+	return h
+}
+
+// extend moves the read deadline forward
+func (h *heartbeat) extend() error {
+	return h.conn.SetReadDeadline(time.Now().Add(h.readDeadline))
+}
+
+// ping sends a ping and expects a pong before the pong timeout. The
+// deadline of an unanswered ping is not moved by the next one.
+func (h *heartbeat) ping() error {
+	if err := h.conn.WriteControl(websocket.PingMessage, []byte{}, time.Now().Add(WriteDeadline)); err != nil {
+		return err
+	}
+
+	if !atomic.CompareAndSwapInt32(&h.waiting, 0, 1) {
+		return nil
+	}
+
+	return h.conn.SetReadDeadline(time.Now().Add(h.pongTimeout))
+}
+
+// pong answers a ping sent by the peer
+func (h *heartbeat) pong(data string) error {
+	if err := h.extend(); err != nil {
+		return err
+	}
+
+	err := h.conn.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(WriteDeadline))
+	if err == websocket.ErrCloseSent {
+		return nil
+	}
+
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+		return nil
+	}
+
+	return err
+}
+
+// reason explains why reading from the connection failed
+func (h *heartbeat) reason(err error) error {
+	if netErr, ok := err.(net.Error); ok && netErr.Timeout() && atomic.LoadInt32(&h.waiting) == 1 {
+		return ErrHeartbeatTimeout
+	}
+
+	return err
+}
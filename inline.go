@@ -0,0 +1,136 @@
+package pho
+
+import "net/http"
+
+var _ Router = &inlineRouter{}
+
+// inlineRouter registers the handlers on a mux without a new namespace and
+// applies its own middlewares to them. It is created by Mux.With and
+// Mux.Group.
+type inlineRouter struct {
+	mux         *Mux
+	middlewares []MiddlewareFunc
+}
+
+// ServeHTTP upgrades the connection with the underlying mux
+func (r *inlineRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mux.ServeHTTP(w, req)
+}
+
+// ServeRPC serves the request with the underlying mux
+func (r *inlineRouter) ServeRPC(w SocketWriter, req *Request) {
+	r.mux.ServeRPC(w, req)
+}
+
+// Use appends one of more middlewares onto the inline stack
+func (r *inlineRouter) Use(middlewares ...MiddlewareFunc) {
+	r.middlewares = append(r.middlewares, middlewares...)
+}
+
+// On registers a handler for particular type of request
+func (r *inlineRouter) On(verb string, handler HandlerFunc) {
+	r.mux.Handle(verb, r.wrap(handler))
+}
+
+// Handle registers a handler for particular type of request
+func (r *inlineRouter) Handle(verb string, handler Handler) {
+	r.mux.Handle(verb, r.wrap(handler))
+}
+
+// NotFound registers a handler of the verbs that cannot be routed
+func (r *inlineRouter) NotFound(handler HandlerFunc) {
+	r.mux.NotFound(r.wrap(handler).ServeRPC)
+}
+
+// MethodNotAllowed registers a handler of the verbs that match a mounted
+// namespace but cannot be routed by it
+func (r *inlineRouter) MethodNotAllowed(handler HandlerFunc) {
+	r.mux.MethodNotAllowed(r.wrap(handler).ServeRPC)
+}
+
+// OnError register a callback function called on error
+func (r *inlineRouter) OnError(fn OnErrorFunc) {
+	r.mux.OnError(fn)
+}
+
+// OnConnect register a callback function called on conection
+func (r *inlineRouter) OnConnect(fn OnConnectFunc) {
+	r.mux.OnConnect(fn)
+}
+
+// OnDisconnect register a callback function called on disconnect
+func (r *inlineRouter) OnDisconnect(fn OnDisconnectFunc) {
+	r.mux.OnDisconnect(fn)
+}
+
+// Mount attaches another handler along the channel
+func (r *inlineRouter) Mount(verb string, handler Handler) {
+	r.mux.Mount(verb, r.wrap(handler))
+}
+
+// Route creates or reuses the subrouter along the verb and passes it to fn
+// as an inline router with the same middlewares
+func (r *inlineRouter) Route(verb string, fn RouterFunc) Router {
+	router := r.mux.Route(verb, nil).(*Mux).With(r.middlewares...)
+
+	if fn != nil {
+		fn(router)
+	}
+
+	return router
+}
+
+// With creates an inline router that applies the middlewares after the
+// middlewares of this router
+func (r *inlineRouter) With(middlewares ...MiddlewareFunc) Router {
+	stack := make([]MiddlewareFunc, 0, len(r.middlewares)+len(middlewares))
+	stack = append(stack, r.middlewares...)
+	stack = append(stack, middlewares...)
+
+	return &inlineRouter{mux: r.mux, middlewares: stack}
+}
+
+// Group creates an inline router with the middlewares of this router and
+// passes it to fn
+func (r *inlineRouter) Group(fn RouterFunc) Router {
+	router := r.With()
+
+	if fn != nil {
+		fn(router)
+	}
+
+	return router
+}
+
+// Routes returns the routes of the underlying mux
+func (r *inlineRouter) Routes() []Route {
+	return r.mux.Routes()
+}
+
+// Middlewares returns the middlewares of the underlying mux
+func (r *inlineRouter) Middlewares() []MiddlewareFunc {
+	return r.mux.Middlewares()
+}
+
+// Close stops all connections of the underlying mux
+func (r *inlineRouter) Close() {
+	r.mux.Close()
+}
+
+// wrap applies the middlewares of the router to the handler
+func (r *inlineRouter) wrap(handler Handler) *inlineHandler {
+	return &inlineHandler{router: r, handler: handler}
+}
+
+// inlineHandler serves the requests through the middlewares of the inline
+// router. The middlewares are chained when the request is served, so the
+// ones appended by Use after the registration are applied as well.
+type inlineHandler struct {
+	router  *inlineRouter
+	handler Handler
+}
+
+// ServeRPC serves the request
+func (h *inlineHandler) ServeRPC(w SocketWriter, r *Request) {
+	Chain(h.router.middlewares, h.handler).ServeRPC(w, r)
+}
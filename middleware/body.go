@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/svett/pho"
+)
+
+// MaxBodySize is a middleware that rejects the requests with a body larger
+// than limit bytes. The rejected requests get a 413 Request Entity Too Large
+// error and *pho.MessageSizeError is reported to the OnError callback of the
+// router. Use pho.MuxOptions.MaxMessageSize to limit the messages read from
+// the connection.
+func MaxBodySize(limit int64) pho.MiddlewareFunc {
+	return func(next pho.Handler) pho.Handler {
+		fn := func(w pho.SocketWriter, r *pho.Request) {
+			if size := int64(len(r.Body)); size > limit {
+				err := &pho.MessageSizeError{
+					SocketID: w.SocketID(),
+					Size:     size,
+					Limit:    limit,
+				}
+
+				w.WriteError(err, http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			next.ServeRPC(w, r)
+		}
+
+		return pho.HandlerFunc(fn)
+	}
+}
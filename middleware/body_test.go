@@ -0,0 +1,70 @@
+package middleware_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/svett/pho"
+	"github.com/svett/pho/middleware"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MaxBodySize", func() {
+	var (
+		router *pho.Mux
+		server *httptest.Server
+		client *pho.Client
+		errs   chan error
+	)
+
+	BeforeEach(func() {
+		errs = make(chan error, 1)
+
+		router = pho.NewMux()
+		router.OnError(func(err error) {
+			errs <- err
+		})
+		router.Use(middleware.MaxBodySize(8))
+		router.On("echo", func(w pho.SocketWriter, r *pho.Request) {
+			w.Write(r.Type, http.StatusOK, r.Body)
+		})
+
+		server = httptest.NewServer(router)
+
+		var err error
+		client, err = pho.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		client.Close()
+		router.Close()
+		server.Close()
+	})
+
+	It("serves the requests within the limit", func() {
+		response, err := client.Call(context.Background(), "echo", []byte(`"small"`))
+		Expect(err).To(BeNil())
+		Expect(string(response.Payload)).To(Equal(`"small"`))
+	})
+
+	It("rejects the larger requests with 413 Request Entity Too Large", func() {
+		_, err := client.Call(context.Background(), "echo", []byte(`"too large"`))
+
+		var responseErr *pho.ResponseError
+		Expect(err).To(BeAssignableToTypeOf(responseErr))
+		Expect(err.(*pho.ResponseError).StatusCode).To(Equal(http.StatusRequestEntityTooLarge))
+
+		var reported error
+		Eventually(errs).Should(Receive(&reported))
+
+		var sizeErr *pho.MessageSizeError
+		Expect(reported).To(BeAssignableToTypeOf(sizeErr))
+		Expect(reported.(*pho.MessageSizeError).Size).To(Equal(int64(11)))
+		Expect(reported.(*pho.MessageSizeError).Limit).To(Equal(int64(8)))
+	})
+})
@@ -0,0 +1,196 @@
+// Package metrics exports Prometheus metrics of the pho mux and its handlers.
+//
+//	m := metrics.New(&metrics.Options{})
+//	m.Instrument(mux)
+//	mux.Use(m.Handler)
+//
+//	http.Handle("/ws", mux)
+//	http.Handle("/metrics", m.ScrapeHandler())
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/svett/pho"
+)
+
+// Options provides the metrics options
+type Options struct {
+	// Namespace of the metrics (defaults to "pho")
+	Namespace string
+	// Registry registers and gathers the metrics (defaults to the default
+	// Prometheus registry)
+	Registry *prometheus.Registry
+	// Buckets of the handler latency histogram in seconds (defaults to
+	// prometheus.DefBuckets)
+	Buckets []float64
+}
+
+// Metrics collects the metrics of the mux and its handlers
+type Metrics struct {
+	namespace  string
+	registerer prometheus.Registerer
+	gatherer   prometheus.Gatherer
+	received   *prometheus.CounterVec
+	sent       *prometheus.CounterVec
+	bytes      *prometheus.CounterVec
+	duration   *prometheus.HistogramVec
+}
+
+// New creates the handler metrics and registers them. The metrics are labeled
+// by the route that matched the verb (ex. "document:{id}:open"). The verbs
+// that do not match a route are labeled "unmatched".
+func New(options *Options) *Metrics {
+	if options == nil {
+		options = &Options{}
+	}
+
+	m := &Metrics{
+		namespace:  options.Namespace,
+		registerer: prometheus.DefaultRegisterer,
+		gatherer:   prometheus.DefaultGatherer,
+	}
+
+	if m.namespace == "" {
+		m.namespace = "pho"
+	}
+
+	if options.Registry != nil {
+		m.registerer = options.Registry
+		m.gatherer = options.Registry
+	}
+
+	buckets := options.Buckets
+	if len(buckets) == 0 {
+		buckets = prometheus.DefBuckets
+	}
+
+	m.received = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: m.namespace,
+		Name:      "messages_received_total",
+		Help:      "Number of requests received per route.",
+	}, []string{"route"})
+
+	m.sent = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: m.namespace,
+		Name:      "messages_sent_total",
+		Help:      "Number of responses sent per route and status code.",
+	}, []string{"route", "status"})
+
+	m.bytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: m.namespace,
+		Name:      "bytes_written_total",
+		Help:      "Number of payload bytes written per route.",
+	}, []string{"route"})
+
+	m.duration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: m.namespace,
+		Name:      "handler_duration_seconds",
+		Help:      "Latency of the handlers per route.",
+		Buckets:   buckets,
+	}, []string{"route"})
+
+	m.registerer.MustRegister(m.received, m.sent, m.bytes, m.duration)
+	return m
+}
+
+// Instrument registers the socket metrics of the mux: active sockets,
+// connects, disconnects and write queue depth. It must be called once per
+// registry.
+func (m *Metrics) Instrument(mux *pho.Mux) {
+	m.registerer.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: m.namespace,
+			Name:      "sockets_active",
+			Help:      "Number of connected sockets.",
+		}, func() float64 {
+			return float64(mux.Stats().ActiveSockets)
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: m.namespace,
+			Name:      "socket_connects_total",
+			Help:      "Number of connected sockets since start.",
+		}, func() float64 {
+			return float64(mux.Stats().Connects)
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: m.namespace,
+			Name:      "socket_disconnects_total",
+			Help:      "Number of disconnected sockets since start.",
+		}, func() float64 {
+			return float64(mux.Stats().Disconnects)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: m.namespace,
+			Name:      "write_queue_depth",
+			Help:      "Number of messages waiting in the write queues of the sockets.",
+		}, func() float64 {
+			return float64(mux.Stats().QueuedMessages)
+		}),
+	)
+}
+
+// Handler is a middleware that records the messages, the bytes written and
+// the latency of every request.
+func (m *Metrics) Handler(next pho.Handler) pho.Handler {
+	fn := func(w pho.SocketWriter, r *pho.Request) {
+		ww := &writer{SocketWriter: w, request: r, metrics: m}
+
+		start := time.Now()
+		defer func() {
+			route := routeOf(r)
+			m.received.WithLabelValues(route).Inc()
+			m.duration.WithLabelValues(route).Observe(time.Since(start).Seconds())
+		}()
+
+		next.ServeRPC(ww, r)
+	}
+
+	return pho.HandlerFunc(fn)
+}
+
+// ScrapeHandler returns the http.Handler that serves the metrics to Prometheus
+func (m *Metrics) ScrapeHandler() http.Handler {
+	return promhttp.InstrumentMetricHandler(m.registerer, promhttp.HandlerFor(m.gatherer, promhttp.HandlerOpts{}))
+}
+
+// routeOf returns the route that matched the verb of the request
+func routeOf(r *pho.Request) string {
+	rctx := pho.GetRouteContext(r.Context())
+	if rctx == nil {
+		return strings.ToLower(r.Type)
+	}
+
+	if route := rctx.RoutePattern(); route != "" {
+		return route
+	}
+
+	return "unmatched"
+}
+
+// writer counts the responses of a request
+type writer struct {
+	pho.SocketWriter
+	request *pho.Request
+	metrics *Metrics
+}
+
+// Write writes the response
+func (w *writer) Write(verb string, code int, data []byte) error {
+	route := routeOf(w.request)
+	w.metrics.sent.WithLabelValues(route, strconv.Itoa(code)).Inc()
+	w.metrics.bytes.WithLabelValues(route).Add(float64(len(data)))
+	return w.SocketWriter.Write(verb, code, data)
+}
+
+// WriteError writes the error response
+func (w *writer) WriteError(err error, code int) error {
+	w.metrics.sent.WithLabelValues(routeOf(w.request), strconv.Itoa(code)).Inc()
+	return w.SocketWriter.WriteError(err, code)
+}
@@ -0,0 +1,174 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/svett/pho"
+	"github.com/svett/pho/middleware/metrics"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// value returns the value of the counter or the gauge, or the number of
+// observations of the histogram, with the provided labels
+func value(registry *prometheus.Registry, name string, labels map[string]string) float64 {
+	families, err := registry.Gather()
+	Expect(err).To(BeNil())
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+
+		for _, metric := range family.GetMetric() {
+			if !hasLabels(metric, labels) {
+				continue
+			}
+
+			switch {
+			case metric.Counter != nil:
+				return metric.GetCounter().GetValue()
+			case metric.Gauge != nil:
+				return metric.GetGauge().GetValue()
+			case metric.Histogram != nil:
+				return float64(metric.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+
+	return 0
+}
+
+func hasLabels(metric *dto.Metric, labels map[string]string) bool {
+	found := 0
+
+	for _, pair := range metric.GetLabel() {
+		if value, ok := labels[pair.GetName()]; ok && value == pair.GetValue() {
+			found++
+		}
+	}
+
+	return found == len(labels)
+}
+
+var _ = Describe("Metrics", func() {
+	var (
+		registry *prometheus.Registry
+		m        *metrics.Metrics
+		router   *pho.Mux
+		server   *httptest.Server
+		client   *pho.Client
+	)
+
+	BeforeEach(func() {
+		registry = prometheus.NewRegistry()
+		m = metrics.New(&metrics.Options{Namespace: "test", Registry: registry})
+
+		router = pho.NewMux()
+		m.Instrument(router)
+		router.Use(m.Handler)
+		router.On("user:{id}:get", func(w pho.SocketWriter, r *pho.Request) {
+			w.Write("user", http.StatusOK, []byte(`"jack"`))
+		})
+		router.On("user:{id}:delete", func(w pho.SocketWriter, r *pho.Request) {
+			w.WriteError(errors.New("The user is not found"), http.StatusNotFound)
+		})
+
+		server = httptest.NewServer(router)
+
+		var err error
+		client, err = pho.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		client.Close()
+		router.Close()
+		server.Close()
+	})
+
+	It("records the successful requests by route", func() {
+		for _, verb := range []string{"user:1:get", "user:2:get"} {
+			_, err := client.Call(context.Background(), verb, []byte(`""`))
+			Expect(err).To(BeNil())
+		}
+
+		route := map[string]string{"route": "user:{id}:get"}
+
+		Eventually(func() float64 {
+			return value(registry, "test_messages_received_total", route)
+		}).Should(Equal(2.0))
+		Expect(value(registry, "test_messages_sent_total", map[string]string{"route": "user:{id}:get", "status": "200"})).To(Equal(2.0))
+		Expect(value(registry, "test_bytes_written_total", route)).To(Equal(12.0))
+		Expect(value(registry, "test_handler_duration_seconds", route)).To(Equal(2.0))
+	})
+
+	It("records the error responses by status code", func() {
+		_, err := client.Call(context.Background(), "user:1:delete", []byte(`""`))
+		Expect(err).To(HaveOccurred())
+
+		Eventually(func() float64 {
+			return value(registry, "test_messages_received_total", map[string]string{"route": "user:{id}:delete"})
+		}).Should(Equal(1.0))
+		Expect(value(registry, "test_messages_sent_total", map[string]string{"route": "user:{id}:delete", "status": "404"})).To(Equal(1.0))
+	})
+
+	It("records the unmatched requests", func() {
+		_, err := client.Call(context.Background(), "order:1:get", []byte(`""`))
+		Expect(err).To(HaveOccurred())
+
+		Eventually(func() float64 {
+			return value(registry, "test_messages_received_total", map[string]string{"route": "unmatched"})
+		}).Should(Equal(1.0))
+	})
+
+	It("records the sockets", func() {
+		Eventually(func() float64 {
+			return value(registry, "test_sockets_active", nil)
+		}).Should(Equal(1.0))
+		Expect(value(registry, "test_socket_connects_total", nil)).To(Equal(1.0))
+		Expect(value(registry, "test_write_queue_depth", nil)).To(Equal(0.0))
+
+		conn, _, err := websocket.DefaultDialer.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+		Expect(err).To(BeNil())
+
+		Eventually(func() float64 {
+			return value(registry, "test_sockets_active", nil)
+		}).Should(Equal(2.0))
+		Expect(value(registry, "test_socket_connects_total", nil)).To(Equal(2.0))
+
+		Expect(conn.Close()).To(Succeed())
+
+		Eventually(func() float64 {
+			return value(registry, "test_socket_disconnects_total", nil)
+		}).Should(Equal(1.0))
+		Expect(value(registry, "test_sockets_active", nil)).To(Equal(1.0))
+	})
+
+	It("serves the metrics to Prometheus", func() {
+		_, err := client.Call(context.Background(), "user:1:get", []byte(`""`))
+		Expect(err).To(BeNil())
+
+		scraper := httptest.NewServer(m.ScrapeHandler())
+		defer scraper.Close()
+
+		response, err := http.Get(scraper.URL)
+		Expect(err).To(BeNil())
+		defer response.Body.Close()
+
+		body, err := io.ReadAll(response.Body)
+		Expect(err).To(BeNil())
+		Expect(string(body)).To(ContainSubstring(`test_messages_sent_total{route="user:{id}:get",status="200"} 1`))
+		Expect(string(body)).To(ContainSubstring("test_sockets_active 1"))
+	})
+})
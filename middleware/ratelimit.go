@@ -0,0 +1,216 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/svett/pho"
+)
+
+// RateLimitKeyFunc returns the key of the token bucket that limits the request
+type RateLimitKeyFunc func(w pho.SocketWriter, r *pho.Request) string
+
+// KeyBySocket limits the requests of every socket separately
+func KeyBySocket(w pho.SocketWriter, r *pho.Request) string {
+	return "socket:" + w.SocketID()
+}
+
+// KeyByIP limits the requests of every remote IP address separately
+func KeyByIP(w pho.SocketWriter, r *pho.Request) string {
+	host, _, err := net.SplitHostPort(w.RemoteAddr())
+	if err != nil {
+		host = w.RemoteAddr()
+	}
+	return "ip:" + host
+}
+
+// KeyByPrincipal limits the requests of every authenticated principal
+// separately. The requests of anonymous sockets are limited per socket.
+func KeyByPrincipal(w pho.SocketWriter, r *pho.Request) string {
+	if principal := pho.SocketPrincipal(w); principal != nil {
+		return fmt.Sprintf("principal:%v", principal)
+	}
+	return KeyBySocket(w, r)
+}
+
+// KeyByVerb limits every verb separately within the buckets of the provided
+// key (ex. KeyByVerb(KeyBySocket) limits every verb of every socket)
+func KeyByVerb(key RateLimitKeyFunc) RateLimitKeyFunc {
+	return func(w pho.SocketWriter, r *pho.Request) string {
+		return key(w, r) + "|verb:" + strings.ToLower(verb(r))
+	}
+}
+
+// RateLimitOptions provides the rate limit options
+type RateLimitOptions struct {
+	// Rate is the number of requests allowed per second
+	Rate float64
+	// Burst is the number of requests allowed at once (defaults to 1)
+	Burst int
+	// Key selects the token bucket of the request (defaults to KeyBySocket)
+	Key RateLimitKeyFunc
+	// Verbs are the limited verbs (all verbs are limited when it is empty)
+	Verbs []string
+	// MaxViolations disconnects the socket with close code 1008 after that
+	// many rejected requests in a row (zero never disconnects)
+	MaxViolations int
+}
+
+// RateLimitError is written to the client when the request is rejected
+type RateLimitError struct {
+	// RetryAfter is the time after which the request is allowed
+	RetryAfter time.Duration
+}
+
+// Error returns the error message
+func (e *RateLimitError) Error() string {
+	return "The rate limit is exceeded"
+}
+
+// Header provides the "Retry-After" header in seconds and the
+// "Retry-After-Ms" header in milliseconds
+func (e *RateLimitError) Header() pho.Header {
+	return pho.Header{
+		"Retry-After":    strconv.FormatInt(int64(math.Ceil(e.RetryAfter.Seconds())), 10),
+		"Retry-After-Ms": strconv.FormatInt(int64(math.Ceil(float64(e.RetryAfter)/float64(time.Millisecond))), 10),
+	}
+}
+
+// RateLimit is a middleware that limits the requests with token buckets. The
+// rejected requests get a 429 Too Many Requests error with the retry-after
+// information in the response header. Repeat offenders can be disconnected
+// with RateLimitOptions.MaxViolations.
+func RateLimit(options *RateLimitOptions) pho.MiddlewareFunc {
+	limiter := newRateLimiter(options)
+
+	verbs := map[string]bool{}
+	for _, verb := range options.Verbs {
+		verbs[strings.ToLower(verb)] = true
+	}
+
+	key := options.Key
+	if key == nil {
+		key = KeyBySocket
+	}
+
+	return func(next pho.Handler) pho.Handler {
+		fn := func(w pho.SocketWriter, r *pho.Request) {
+			if len(verbs) > 0 && !verbs[strings.ToLower(verb(r))] {
+				next.ServeRPC(w, r)
+				return
+			}
+
+			allowed, retryAfter, violations := limiter.take(key(w, r), time.Now())
+			if allowed {
+				next.ServeRPC(w, r)
+				return
+			}
+
+			err := &RateLimitError{RetryAfter: retryAfter}
+			w.WriteError(err, http.StatusTooManyRequests)
+
+			if options.MaxViolations > 0 && violations >= options.MaxViolations {
+				pho.Disconnect(r.Context(), websocket.ClosePolicyViolation, err)
+			}
+		}
+
+		return pho.HandlerFunc(fn)
+	}
+}
+
+// verb returns the verb sent by the client
+func verb(r *pho.Request) string {
+	if rctx := pho.GetRouteContext(r.Context()); rctx != nil {
+		return rctx.Verb
+	}
+	return r.Type
+}
+
+// rateLimitSweepInterval is how often the idle buckets are removed
+const rateLimitSweepInterval = time.Minute
+
+// bucket is a token bucket
+type bucket struct {
+	tokens     float64
+	last       time.Time
+	violations int
+}
+
+// rateLimiter keeps the token buckets
+type rateLimiter struct {
+	mu        sync.Mutex
+	rate      float64
+	burst     float64
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func newRateLimiter(options *RateLimitOptions) *rateLimiter {
+	burst := options.Burst
+	if burst <= 0 {
+		burst = 1
+	}
+
+	return &rateLimiter{
+		rate:      options.Rate,
+		burst:     float64(burst),
+		buckets:   map[string]*bucket{},
+		lastSweep: time.Now(),
+	}
+}
+
+// take takes a token from the bucket of the key. When the bucket is empty,
+// it returns the time after which a token is available and the number of
+// rejections in a row.
+func (l *rateLimiter) take(key string, now time.Time) (bool, time.Duration, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		b.violations = 0
+		return true, 0, 0
+	}
+
+	b.violations++
+
+	if l.rate <= 0 {
+		return false, time.Duration(math.MaxInt64), b.violations
+	}
+
+	return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second)), b.violations
+}
+
+// sweep removes the buckets that have been refilled
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitSweepInterval || l.rate <= 0 {
+		return
+	}
+
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
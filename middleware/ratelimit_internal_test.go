@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/svett/pho"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// fakeWriter provides the socket properties used by the rate limit keys
+type fakeWriter struct {
+	pho.SocketWriter
+	id       string
+	addr     string
+	metadata pho.Metadata
+}
+
+func (w *fakeWriter) SocketID() string       { return w.id }
+func (w *fakeWriter) RemoteAddr() string     { return w.addr }
+func (w *fakeWriter) Metadata() pho.Metadata { return w.metadata }
+
+var _ = Describe("rateLimiter", func() {
+	var (
+		limiter *rateLimiter
+		now     time.Time
+	)
+
+	BeforeEach(func() {
+		limiter = newRateLimiter(&RateLimitOptions{Rate: 2, Burst: 3})
+		now = limiter.lastSweep
+	})
+
+	It("allows a burst of requests", func() {
+		for i := 0; i < 3; i++ {
+			allowed, _, _ := limiter.take("jack", now)
+			Expect(allowed).To(BeTrue())
+		}
+
+		allowed, retryAfter, violations := limiter.take("jack", now)
+		Expect(allowed).To(BeFalse())
+		Expect(retryAfter).To(Equal(500 * time.Millisecond))
+		Expect(violations).To(Equal(1))
+	})
+
+	It("refills the bucket at the rate", func() {
+		for i := 0; i < 3; i++ {
+			limiter.take("jack", now)
+		}
+
+		allowed, retryAfter, _ := limiter.take("jack", now.Add(250*time.Millisecond))
+		Expect(allowed).To(BeFalse())
+		Expect(retryAfter).To(Equal(250 * time.Millisecond))
+
+		allowed, _, _ = limiter.take("jack", now.Add(500*time.Millisecond))
+		Expect(allowed).To(BeTrue())
+	})
+
+	It("limits every key separately", func() {
+		for i := 0; i < 3; i++ {
+			limiter.take("jack", now)
+		}
+
+		allowed, _, _ := limiter.take("jill", now)
+		Expect(allowed).To(BeTrue())
+	})
+
+	It("counts the rejections in a row", func() {
+		for i := 0; i < 3; i++ {
+			limiter.take("jack", now)
+		}
+
+		for i := 1; i <= 3; i++ {
+			_, _, violations := limiter.take("jack", now)
+			Expect(violations).To(Equal(i))
+		}
+
+		allowed, _, violations := limiter.take("jack", now.Add(time.Second))
+		Expect(allowed).To(BeTrue())
+		Expect(violations).To(BeZero())
+
+		_, _, violations = limiter.take("jack", now.Add(time.Second))
+		Expect(violations).To(BeZero())
+	})
+
+	It("removes the refilled buckets", func() {
+		limiter.take("jack", now)
+		limiter.take("jill", now.Add(rateLimitSweepInterval-time.Second))
+		limiter.take("jill", now.Add(rateLimitSweepInterval-time.Second))
+		limiter.take("jill", now.Add(rateLimitSweepInterval-time.Second))
+		Expect(limiter.buckets).To(HaveLen(2))
+
+		limiter.take("joe", now.Add(rateLimitSweepInterval))
+		Expect(limiter.buckets).To(HaveLen(2))
+		Expect(limiter.buckets).To(HaveKey("jill"))
+		Expect(limiter.buckets).To(HaveKey("joe"))
+	})
+
+	Context("when the rate is zero", func() {
+		BeforeEach(func() {
+			limiter = newRateLimiter(&RateLimitOptions{})
+		})
+
+		It("allows a single request", func() {
+			allowed, _, _ := limiter.take("jack", now)
+			Expect(allowed).To(BeTrue())
+
+			allowed, _, _ = limiter.take("jack", now.Add(time.Hour))
+			Expect(allowed).To(BeFalse())
+		})
+	})
+})
+
+var _ = Describe("RateLimitKeyFunc", func() {
+	var (
+		w *fakeWriter
+		r *pho.Request
+	)
+
+	BeforeEach(func() {
+		w = &fakeWriter{id: "42", addr: "10.0.0.1:5000", metadata: pho.Metadata{}}
+		r = &pho.Request{Type: "User:Create"}
+	})
+
+	It("keys the requests by socket", func() {
+		Expect(KeyBySocket(w, r)).To(Equal("socket:42"))
+	})
+
+	It("keys the requests by IP address", func() {
+		Expect(KeyByIP(w, r)).To(Equal("ip:10.0.0.1"))
+
+		w.addr = "10.0.0.1"
+		Expect(KeyByIP(w, r)).To(Equal("ip:10.0.0.1"))
+	})
+
+	It("keys the requests by principal", func() {
+		Expect(KeyByPrincipal(w, r)).To(Equal("socket:42"))
+
+		w.metadata[pho.MetadataPrincipalKey] = "jack"
+		Expect(KeyByPrincipal(w, r)).To(Equal("principal:jack"))
+	})
+
+	It("keys the requests by verb", func() {
+		Expect(KeyByVerb(KeyByIP)(w, r)).To(Equal("ip:10.0.0.1|verb:user:create"))
+	})
+})
@@ -0,0 +1,111 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gorilla/websocket"
+	"github.com/svett/pho"
+	"github.com/svett/pho/middleware"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RateLimit", func() {
+	var (
+		router  *pho.Mux
+		server  *httptest.Server
+		client  *pho.Client
+		options *middleware.RateLimitOptions
+	)
+
+	BeforeEach(func() {
+		options = &middleware.RateLimitOptions{Rate: 0.001, Burst: 1}
+	})
+
+	JustBeforeEach(func() {
+		router = pho.NewMux()
+		router.Use(middleware.Logger)
+		router.Use(middleware.RateLimit(options))
+		router.On("echo", func(w pho.SocketWriter, r *pho.Request) {
+			w.Write(r.Type, http.StatusOK, r.Body)
+		})
+		router.On("ping", func(w pho.SocketWriter, r *pho.Request) {
+			w.Write(r.Type, http.StatusOK, r.Body)
+		})
+
+		server = httptest.NewServer(router)
+
+		var err error
+		client, err = pho.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		client.Close()
+		router.Close()
+		server.Close()
+	})
+
+	It("rejects the requests over the limit with 429 Too Many Requests", func() {
+		_, err := client.Call(context.Background(), "echo", []byte(`"1"`))
+		Expect(err).To(BeNil())
+
+		_, err = client.Call(context.Background(), "echo", []byte(`"2"`))
+
+		var responseErr *pho.ResponseError
+		Expect(errors.As(err, &responseErr)).To(BeTrue())
+		Expect(responseErr.StatusCode).To(Equal(http.StatusTooManyRequests))
+		Expect(responseErr.Message).To(Equal("The rate limit is exceeded"))
+		Expect(responseErr.Header).To(HaveKeyWithValue("Retry-After", "1000"))
+		Expect(responseErr.Header).To(HaveKey("Retry-After-Ms"))
+	})
+
+	Context("when the verbs are provided", func() {
+		BeforeEach(func() {
+			options.Verbs = []string{"ECHO"}
+		})
+
+		It("limits only those verbs", func() {
+			for i := 0; i < 3; i++ {
+				_, err := client.Call(context.Background(), "ping", []byte(`""`))
+				Expect(err).To(BeNil())
+			}
+
+			_, err := client.Call(context.Background(), "echo", []byte(`""`))
+			Expect(err).To(BeNil())
+
+			_, err = client.Call(context.Background(), "echo", []byte(`""`))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Context("when the max violations are provided", func() {
+		BeforeEach(func() {
+			options.MaxViolations = 2
+		})
+
+		It("disconnects the socket behind the wrapped writer", func() {
+			reasons := make(chan error, 1)
+			client.OnDisconnect(func(err error) {
+				reasons <- err
+			})
+
+			_, err := client.Call(context.Background(), "echo", []byte(`""`))
+			Expect(err).To(BeNil())
+
+			for i := 0; i < 2; i++ {
+				_, err = client.Call(context.Background(), "echo", []byte(`""`))
+				Expect(err).To(HaveOccurred())
+			}
+
+			var reason error
+			Eventually(reasons).Should(Receive(&reason))
+			Expect(websocket.IsCloseError(reason, websocket.ClosePolicyViolation)).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,46 @@
+package middleware
+
+// The original work was derived from Goji's middleware, source:
+// https://github.com/zenazn/goji/tree/master/web/middleware
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	"github.com/svett/pho"
+)
+
+// PanicLoggerFunc is called with every panic recovered by the Recoverer
+type PanicLoggerFunc func(w pho.SocketWriter, r *pho.Request, err *pho.PanicError)
+
+// Recoverer is a middleware that recovers from panics, writes an internal
+// server error to the client and reports the panic with its stack trace to
+// the OnError callback of the router through SocketWriter.WriteError. The
+// socket stays connected.
+func Recoverer(next pho.Handler) pho.Handler {
+	return RecovererWithLogger(nil)(next)
+}
+
+// RecovererWithLogger returns a Recoverer that also passes every panic to
+// the provided logger.
+func RecovererWithLogger(logger PanicLoggerFunc) pho.MiddlewareFunc {
+	return func(next pho.Handler) pho.Handler {
+		fn := func(w pho.SocketWriter, r *pho.Request) {
+			defer func() {
+				if rvr := recover(); rvr != nil {
+					err := &pho.PanicError{Value: rvr, Stack: debug.Stack()}
+
+					if logger != nil {
+						logger(w, r, err)
+					}
+
+					w.WriteError(err, http.StatusInternalServerError)
+				}
+			}()
+
+			next.ServeRPC(w, r)
+		}
+
+		return pho.HandlerFunc(fn)
+	}
+}
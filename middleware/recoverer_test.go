@@ -0,0 +1,88 @@
+package middleware_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/svett/pho"
+	"github.com/svett/pho/middleware"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Recoverer", func() {
+	var (
+		router *pho.Mux
+		server *httptest.Server
+		client *pho.Client
+		panics chan *pho.PanicError
+		errs   chan error
+	)
+
+	BeforeEach(func() {
+		panics = make(chan *pho.PanicError, 1)
+		errs = make(chan error, 1)
+
+		router = pho.NewMux()
+		router.OnError(func(err error) {
+			errs <- err
+		})
+		router.Use(middleware.RecovererWithLogger(func(w pho.SocketWriter, r *pho.Request, err *pho.PanicError) {
+			panics <- err
+		}))
+		router.On("panic", func(w pho.SocketWriter, r *pho.Request) {
+			panic("oh no")
+		})
+		router.On("echo", func(w pho.SocketWriter, r *pho.Request) {
+			w.Write(r.Type, http.StatusOK, r.Body)
+		})
+
+		server = httptest.NewServer(router)
+
+		var err error
+		client, err = pho.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		client.Close()
+		router.Close()
+		server.Close()
+	})
+
+	It("responds with an internal server error", func() {
+		_, err := client.Call(context.Background(), "panic", []byte(`""`))
+		Expect(err).To(Equal(&pho.ResponseError{
+			StatusCode: http.StatusInternalServerError,
+			Message:    "panic: oh no",
+		}))
+	})
+
+	It("passes the panic with its stack to the logger", func() {
+		_, err := client.Call(context.Background(), "panic", []byte(`""`))
+		Expect(err).To(HaveOccurred())
+
+		var perr *pho.PanicError
+		Eventually(panics).Should(Receive(&perr))
+		Expect(perr.Value).To(Equal("oh no"))
+		Expect(string(perr.Stack)).To(ContainSubstring("recoverer_test.go"))
+	})
+
+	It("reports the panic to the OnError callback", func() {
+		_, err := client.Call(context.Background(), "panic", []byte(`""`))
+		Expect(err).To(HaveOccurred())
+		Eventually(errs).Should(Receive(BeAssignableToTypeOf(&pho.PanicError{})))
+	})
+
+	It("keeps the socket connected", func() {
+		_, err := client.Call(context.Background(), "panic", []byte(`""`))
+		Expect(err).To(HaveOccurred())
+
+		response, err := client.Call(context.Background(), "echo", []byte(`"alive"`))
+		Expect(err).To(BeNil())
+		Expect(string(response.Payload)).To(Equal(`"alive"`))
+	})
+})
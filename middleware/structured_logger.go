@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"context"
+	"log/slog"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/svett/pho"
+)
+
+// RedactedValue replaces the values of the redacted header keys
+const RedactedValue = "[REDACTED]"
+
+// StructuredLoggerOptions provides the structured logger options
+type StructuredLoggerOptions struct {
+	// Levels are the log levels per status class (ex. 4 for the 4xx status
+	// codes). The defaults are slog.LevelError for 5xx, slog.LevelWarn for
+	// 4xx and slog.LevelInfo otherwise.
+	Levels map[int]slog.Level
+	// SampleRate is the fraction of the requests logged below
+	// slog.LevelWarn (zero logs every request)
+	SampleRate float64
+	// RedactHeaders are the request header keys whose values are redacted
+	RedactHeaders []string
+}
+
+// StructuredLogger returns a middleware that logs every request with the
+// provided *slog.Logger once it is served. The record has the verb, socket
+// ID, remote address, request ID, status, bytes written, duration and
+// request header as attributes.
+func StructuredLogger(logger *slog.Logger, options *StructuredLoggerOptions) pho.MiddlewareFunc {
+	if options == nil {
+		options = &StructuredLoggerOptions{}
+	}
+
+	levels := map[int]slog.Level{
+		4: slog.LevelWarn,
+		5: slog.LevelError,
+	}
+
+	for class, level := range options.Levels {
+		levels[class] = level
+	}
+
+	redacted := map[string]bool{}
+	for _, key := range options.RedactHeaders {
+		redacted[strings.ToLower(key)] = true
+	}
+
+	return func(next pho.Handler) pho.Handler {
+		fn := func(w pho.SocketWriter, r *pho.Request) {
+			ww := NewWrapSocketWriter(w)
+			r = r.WithContext(context.WithValue(r.Context(), WrapResponseWriterCtxKey, ww))
+
+			t1 := time.Now()
+			defer func() {
+				status := ww.Status()
+
+				level, ok := levels[status/100]
+				if !ok {
+					level = slog.LevelInfo
+				}
+
+				if level < slog.LevelWarn && options.SampleRate > 0 && rand.Float64() >= options.SampleRate {
+					return
+				}
+
+				if !logger.Enabled(r.Context(), level) {
+					return
+				}
+
+				logger.LogAttrs(r.Context(), level, "request served",
+					slog.String("verb", verb(r)),
+					slog.String("socket_id", w.SocketID()),
+					slog.String("remote_addr", w.RemoteAddr()),
+					slog.String("request_id", GetReqID(r.Context())),
+					slog.Int("status", status),
+					slog.Int("bytes", ww.BytesWritten()),
+					slog.Duration("duration", time.Since(t1)),
+					headerAttr(r.Header, redacted),
+				)
+			}()
+
+			next.ServeRPC(ww, r)
+		}
+
+		return pho.HandlerFunc(fn)
+	}
+}
+
+// headerAttr returns the request header as a group with the redacted values
+func headerAttr(header pho.Header, redacted map[string]bool) slog.Attr {
+	keys := header.Keys()
+	sort.Strings(keys)
+
+	attrs := make([]any, 0, len(keys))
+
+	for _, key := range keys {
+		value := header[key]
+		if redacted[strings.ToLower(key)] {
+			value = RedactedValue
+		}
+		attrs = append(attrs, slog.String(key, value))
+	}
+
+	return slog.Group("header", attrs...)
+}
@@ -0,0 +1,194 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/svett/pho"
+	"github.com/svett/pho/middleware"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// recordingHandler keeps the attributes of the logged records
+type recordingHandler struct {
+	mu      sync.Mutex
+	records []map[string]interface{}
+}
+
+func (h *recordingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return true
+}
+
+func (h *recordingHandler) Handle(ctx context.Context, record slog.Record) error {
+	attrs := map[string]interface{}{
+		"level":   record.Level,
+		"message": record.Message,
+	}
+
+	record.Attrs(func(attr slog.Attr) bool {
+		if attr.Value.Kind() == slog.KindGroup {
+			for _, member := range attr.Value.Group() {
+				attrs[attr.Key+"."+member.Key] = member.Value.Any()
+			}
+			return true
+		}
+
+		attrs[attr.Key] = attr.Value.Any()
+		return true
+	})
+
+	h.mu.Lock()
+	h.records = append(h.records, attrs)
+	h.mu.Unlock()
+	return nil
+}
+
+func (h *recordingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h
+}
+
+func (h *recordingHandler) WithGroup(name string) slog.Handler {
+	return h
+}
+
+func (h *recordingHandler) Records() []map[string]interface{} {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]map[string]interface{}{}, h.records...)
+}
+
+var _ = Describe("StructuredLogger", func() {
+	var (
+		handler *recordingHandler
+		options *middleware.StructuredLoggerOptions
+		router  *pho.Mux
+		server  *httptest.Server
+		client  *pho.Client
+	)
+
+	BeforeEach(func() {
+		handler = &recordingHandler{}
+		options = &middleware.StructuredLoggerOptions{}
+	})
+
+	JustBeforeEach(func() {
+		router = pho.NewMux()
+		router.Use(middleware.RequestID)
+		router.Use(middleware.StructuredLogger(slog.New(handler), options))
+		router.On("echo", func(w pho.SocketWriter, r *pho.Request) {
+			w.Write(r.Type, http.StatusOK, r.Body)
+		})
+		router.On("missing", func(w pho.SocketWriter, r *pho.Request) {
+			w.WriteError(errors.New("The resource is not found"), http.StatusNotFound)
+		})
+		router.On("fail", func(w pho.SocketWriter, r *pho.Request) {
+			w.WriteError(errors.New("The server failed"), http.StatusInternalServerError)
+		})
+
+		server = httptest.NewServer(router)
+
+		var err error
+		client, err = pho.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		client.Close()
+		router.Close()
+		server.Close()
+	})
+
+	It("logs the served requests with their attributes", func() {
+		_, err := client.Call(context.Background(), "echo", []byte(`"jack"`))
+		Expect(err).To(BeNil())
+
+		Eventually(handler.Records).Should(HaveLen(1))
+
+		record := handler.Records()[0]
+		Expect(record).To(HaveKeyWithValue("message", "request served"))
+		Expect(record).To(HaveKeyWithValue("level", slog.LevelInfo))
+		Expect(record).To(HaveKeyWithValue("verb", "echo"))
+		Expect(record).To(HaveKeyWithValue("status", int64(http.StatusOK)))
+		Expect(record).To(HaveKeyWithValue("bytes", int64(6)))
+		Expect(record).To(HaveKeyWithValue("socket_id", Not(BeEmpty())))
+		Expect(record).To(HaveKeyWithValue("remote_addr", Not(BeEmpty())))
+		Expect(record).To(HaveKeyWithValue("request_id", Not(BeEmpty())))
+		Expect(record).To(HaveKey("duration"))
+	})
+
+	It("logs the requests at the level of their status class", func() {
+		for _, verb := range []string{"echo", "missing", "fail"} {
+			Expect(client.Write(verb, []byte(`""`))).To(Succeed())
+		}
+
+		Eventually(handler.Records).Should(HaveLen(3))
+
+		levels := map[interface{}]interface{}{}
+		for _, record := range handler.Records() {
+			levels[record["verb"]] = record["level"]
+		}
+
+		Expect(levels).To(Equal(map[interface{}]interface{}{
+			"echo":    slog.LevelInfo,
+			"missing": slog.LevelWarn,
+			"fail":    slog.LevelError,
+		}))
+	})
+
+	Context("when the levels are provided", func() {
+		BeforeEach(func() {
+			options.Levels = map[int]slog.Level{4: slog.LevelInfo}
+		})
+
+		It("overrides the default levels", func() {
+			Expect(client.Write("missing", []byte(`""`))).To(Succeed())
+
+			Eventually(handler.Records).Should(HaveLen(1))
+			Expect(handler.Records()[0]).To(HaveKeyWithValue("level", slog.LevelInfo))
+		})
+	})
+
+	Context("when the requests are sampled", func() {
+		BeforeEach(func() {
+			options.SampleRate = 0.000001
+		})
+
+		It("logs the warnings and the errors only", func() {
+			for i := 0; i < 10; i++ {
+				Expect(client.Write("echo", []byte(`""`))).To(Succeed())
+			}
+			Expect(client.Write("fail", []byte(`""`))).To(Succeed())
+
+			Eventually(handler.Records).Should(HaveLen(1))
+			Consistently(handler.Records).Should(HaveLen(1))
+			Expect(handler.Records()[0]).To(HaveKeyWithValue("verb", "fail"))
+		})
+	})
+
+	Context("when the header keys are redacted", func() {
+		BeforeEach(func() {
+			options.RedactHeaders = []string{"authorization"}
+		})
+
+		It("replaces their values", func() {
+			Expect(client.Do(&pho.Request{
+				Type:   "echo",
+				Header: pho.Header{"Authorization": "Bearer secret", "Locale": "en"},
+				Body:   []byte(`""`),
+			})).To(Succeed())
+
+			Eventually(handler.Records).Should(HaveLen(1))
+
+			record := handler.Records()[0]
+			Expect(record).To(HaveKeyWithValue("header.Authorization", middleware.RedactedValue))
+			Expect(record).To(HaveKeyWithValue("header.Locale", "en"))
+		})
+	})
+})
@@ -0,0 +1,206 @@
+// Package tracing traces the pho RPCs with OpenTelemetry.
+//
+// The client injects the trace context into the header of the calls and of
+// the requests sent by Do and Write:
+//
+//	client, err := pho.DialWithOptions(url, &pho.ClientOptions{
+//		Interceptor: tracing.ClientInterceptor(&tracing.Options{}),
+//	})
+//
+// The server extracts it and creates a child span for every request:
+//
+//	mux.Use(tracing.Middleware(&tracing.Options{}))
+package tracing
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/svett/pho"
+)
+
+// ScopeName is the instrumentation scope of the tracer
+const ScopeName = "github.com/svett/pho/middleware/tracing"
+
+// The span attributes
+const (
+	VerbKey         = attribute.Key("pho.verb")
+	SocketIDKey     = attribute.Key("pho.socket_id")
+	RequestIDKey    = attribute.Key("pho.request_id")
+	StatusCodeKey   = attribute.Key("pho.status_code")
+	RequestSizeKey  = attribute.Key("pho.request.size")
+	ResponseSizeKey = attribute.Key("pho.response.size")
+)
+
+// Options provides the tracing options
+type Options struct {
+	// TracerProvider creates the tracer (defaults to the global provider)
+	TracerProvider trace.TracerProvider
+	// Propagator injects and extracts the trace context (defaults to the
+	// global propagator)
+	Propagator propagation.TextMapPropagator
+}
+
+func (o *Options) tracer() trace.Tracer {
+	provider := o.TracerProvider
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return provider.Tracer(ScopeName)
+}
+
+func (o *Options) propagator() propagation.TextMapPropagator {
+	if o.Propagator != nil {
+		return o.Propagator
+	}
+	return otel.GetTextMapPropagator()
+}
+
+// ClientInterceptor returns a pho.CallInterceptor that creates a client span
+// for every call and every request sent by Do or Write, and injects its
+// context into the request header
+func ClientInterceptor(options *Options) pho.CallInterceptor {
+	if options == nil {
+		options = &Options{}
+	}
+
+	tracer := options.tracer()
+	propagator := options.propagator()
+
+	return func(ctx context.Context, request *pho.Request, invoke pho.CallInvoker) (*pho.Response, error) {
+		ctx, span := tracer.Start(ctx, request.Type,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(
+				VerbKey.String(request.Type),
+				RequestIDKey.String(request.ID),
+				RequestSizeKey.Int(len(request.Body)),
+			),
+		)
+		defer span.End()
+
+		if request.Header == nil {
+			request.Header = pho.Header{}
+		}
+
+		propagator.Inject(ctx, request.Header)
+
+		response, err := invoke(ctx, request)
+		if err != nil {
+			var rerr *pho.ResponseError
+			if errors.As(err, &rerr) {
+				span.SetAttributes(StatusCodeKey.Int(rerr.StatusCode))
+			}
+
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+
+		// the requests sent by Do and Write do not wait for a response
+		if response == nil {
+			return nil, nil
+		}
+
+		span.SetAttributes(
+			StatusCodeKey.Int(response.StatusCode),
+			ResponseSizeKey.Int(len(response.Payload)),
+		)
+
+		return response, nil
+	}
+}
+
+// Middleware returns a middleware that extracts the trace context from the
+// request header and creates a server span for every request. The trace
+// context of the span is sent back in the response header.
+func Middleware(options *Options) pho.MiddlewareFunc {
+	if options == nil {
+		options = &Options{}
+	}
+
+	tracer := options.tracer()
+	propagator := options.propagator()
+
+	return func(next pho.Handler) pho.Handler {
+		fn := func(w pho.SocketWriter, r *pho.Request) {
+			ctx := r.Context()
+			if r.Header != nil {
+				ctx = propagator.Extract(ctx, r.Header)
+			}
+
+			verb := verbOf(r)
+
+			ctx, span := tracer.Start(ctx, verb,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					VerbKey.String(verb),
+					SocketIDKey.String(w.SocketID()),
+					RequestIDKey.String(r.ID),
+					RequestSizeKey.Int(len(r.Body)),
+				),
+			)
+			defer span.End()
+
+			if header := pho.ResponseHeader(ctx); header != nil {
+				propagator.Inject(ctx, header)
+			}
+
+			ww := &writer{SocketWriter: w, span: span}
+			next.ServeRPC(ww, r.WithContext(ctx))
+
+			// the span is named after the route that matched the verb
+			if rctx := pho.GetRouteContext(ctx); rctx != nil && rctx.RoutePattern() != "" {
+				span.SetName(rctx.RoutePattern())
+			}
+
+			if ww.status > 0 {
+				span.SetAttributes(StatusCodeKey.Int(ww.status))
+			}
+			span.SetAttributes(ResponseSizeKey.Int(ww.bytes))
+		}
+
+		return pho.HandlerFunc(fn)
+	}
+}
+
+// verbOf returns the verb sent by the client
+func verbOf(r *pho.Request) string {
+	if rctx := pho.GetRouteContext(r.Context()); rctx != nil {
+		return strings.ToLower(rctx.Verb)
+	}
+	return strings.ToLower(r.Type)
+}
+
+// writer records the responses in the span
+type writer struct {
+	pho.SocketWriter
+	span   trace.Span
+	status int
+	bytes  int
+}
+
+// Write writes the response
+func (w *writer) Write(verb string, code int, data []byte) error {
+	w.status = code
+	w.bytes += len(data)
+	return w.SocketWriter.Write(verb, code, data)
+}
+
+// WriteError writes the error response and records the error in the span
+func (w *writer) WriteError(err error, code int) error {
+	w.status = code
+	w.span.RecordError(err)
+
+	if code >= 500 {
+		w.span.SetStatus(codes.Error, err.Error())
+	}
+
+	return w.SocketWriter.WriteError(err, code)
+}
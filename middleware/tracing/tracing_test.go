@@ -0,0 +1,127 @@
+package tracing_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/svett/pho"
+	"github.com/svett/pho/middleware/tracing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tracing", func() {
+	var (
+		router   *pho.Mux
+		server   *httptest.Server
+		client   *pho.Client
+		exporter *tracetest.InMemoryExporter
+	)
+
+	BeforeEach(func() {
+		exporter = tracetest.NewInMemoryExporter()
+
+		options := &tracing.Options{
+			TracerProvider: sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)),
+			Propagator:     propagation.TraceContext{},
+		}
+
+		router = pho.NewMux()
+		router.Use(tracing.Middleware(options))
+		server = httptest.NewServer(router)
+
+		var err error
+		client, err = pho.DialWithOptions(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), &pho.ClientOptions{
+			Interceptor: tracing.ClientInterceptor(options),
+		})
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		client.Close()
+		router.Close()
+		server.Close()
+	})
+
+	spans := func() tracetest.SpanStubs {
+		return exporter.GetSpans()
+	}
+
+	It("traces the calls on both sides", func() {
+		router.Route("document", func(r pho.Router) {
+			r.On("{id}:open", func(w pho.SocketWriter, r *pho.Request) {
+				Expect(w.Write("document", http.StatusOK, r.Body)).To(Succeed())
+			})
+		})
+
+		response, err := client.Call(context.Background(), "document:42:open", []byte(`"hello"`))
+		Expect(err).To(BeNil())
+
+		Eventually(spans).Should(HaveLen(2))
+
+		server, client := spans()[0], spans()[1]
+		Expect(server.SpanKind).To(Equal(trace.SpanKindServer))
+		Expect(server.Name).To(Equal("document:{id}:open"))
+		Expect(client.SpanKind).To(Equal(trace.SpanKindClient))
+		Expect(client.Name).To(Equal("document:42:open"))
+
+		Expect(server.SpanContext.TraceID()).To(Equal(client.SpanContext.TraceID()))
+		Expect(server.Parent.SpanID()).To(Equal(client.SpanContext.SpanID()))
+
+		Expect(server.Attributes).To(ContainElement(tracing.VerbKey.String("document:42:open")))
+		Expect(server.Attributes).To(ContainElement(tracing.StatusCodeKey.Int(http.StatusOK)))
+		Expect(server.Attributes).To(ContainElement(tracing.RequestSizeKey.Int(7)))
+		Expect(server.Attributes).To(ContainElement(tracing.ResponseSizeKey.Int(7)))
+		Expect(server.Attributes).To(ContainElement(HaveField("Key", tracing.SocketIDKey)))
+
+		Expect(response.Header).To(HaveKeyWithValue("traceparent", ContainSubstring(client.SpanContext.TraceID().String())))
+	})
+
+	It("traces the requests sent by Write", func() {
+		router.On("notify", func(w pho.SocketWriter, r *pho.Request) {
+			Expect(w.Write("notified", http.StatusOK, r.Body)).To(Succeed())
+		})
+
+		responses := make(chan *pho.Response, 1)
+		client.On("notified", func(response *pho.Response) {
+			responses <- response
+		})
+
+		Expect(client.Write("notify", []byte(`"hello"`))).To(Succeed())
+
+		var response *pho.Response
+		Eventually(responses).Should(Receive(&response))
+		Eventually(spans).Should(HaveLen(2))
+
+		client, server := spans()[0], spans()[1]
+		Expect(client.SpanKind).To(Equal(trace.SpanKindClient))
+		Expect(server.SpanKind).To(Equal(trace.SpanKindServer))
+		Expect(server.Parent.SpanID()).To(Equal(client.SpanContext.SpanID()))
+
+		Expect(response.Header).To(HaveKeyWithValue("traceparent", ContainSubstring(client.SpanContext.TraceID().String())))
+	})
+
+	It("records the error responses", func() {
+		router.On("fail", func(w pho.SocketWriter, r *pho.Request) {
+			w.WriteError(fmt.Errorf("oh no"), http.StatusInternalServerError)
+		})
+
+		_, err := client.Call(context.Background(), "fail", []byte(`""`))
+		Expect(err).To(HaveOccurred())
+
+		Eventually(spans).Should(HaveLen(2))
+
+		for _, span := range spans() {
+			Expect(span.Status.Description).To(Equal("oh no"))
+			Expect(span.Attributes).To(ContainElement(tracing.StatusCodeKey.Int(http.StatusInternalServerError)))
+		}
+	})
+})
@@ -34,10 +34,16 @@ func NewWrapSocketWriter(socket pho.SocketWriter) WrapSocketWriter {
 // Write writes the response
 func (w *writer) Write(verb string, code int, data []byte) error {
 	w.code = code
-	w.bytes = len(data)
+	w.bytes += len(data)
 	return w.SocketWriter.Write(verb, code, data)
 }
 
+// WriteError writes the error response
+func (w *writer) WriteError(err error, code int) error {
+	w.code = code
+	return w.SocketWriter.WriteError(err, code)
+}
+
 // Status returns the status
 func (b *writer) Status() int {
 	return b.code
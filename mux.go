@@ -1,17 +1,81 @@
 package pho
 
 import (
+	"context"
 	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
 
+// shutdownPollInterval is how often Shutdown checks for idle sockets
+const shutdownPollInterval = 10 * time.Millisecond
+
 // Sockets is the list of all sockets
 type WebSockets map[string]ResponseWriter
 
+// MuxOptions provides the mux options
+type MuxOptions struct {
+	// WriteQueueSize is the number of responses buffered per socket
+	WriteQueueSize int
+	// WriteQueuePolicy is applied when the write queue of a socket is full
+	WriteQueuePolicy QueuePolicy
+	// PingInterval is the period of sending pings to each socket
+	// (negative value disables the pings)
+	PingInterval time.Duration
+	// PongTimeout is the time allowed to the socket to answer a ping
+	PongTimeout time.Duration
+	// ReadDeadline is the time allowed to read the next message
+	ReadDeadline time.Duration
+	// CloseCode is sent to the sockets on shutdown (defaults to 1001)
+	CloseCode int
+	// CloseReason is sent to the sockets on shutdown
+	CloseReason string
+	// CloseTimeout is the time allowed to the socket to answer the close
+	// frame before the connection is closed (defaults to 10 seconds)
+	CloseTimeout time.Duration
+	// Recover recovers from handler panics and writes an internal server
+	// error to the socket instead of crashing the process
+	Recover bool
+	// MaxConcurrency is the number of requests of a socket served at a time.
+	// When it is zero the requests of a socket are served one by one. When
+	// it is positive every request gets a copy of the socket metadata.
+	MaxConcurrency int
+	// WorkerPoolSize limits the number of requests served at a time across
+	// all sockets when MaxConcurrency is positive (zero means no limit)
+	WorkerPoolSize int
+	// OrderedVerbs are served one by one in the order of arrival per socket
+	// when MaxConcurrency is positive (ex. edits of the same document)
+	OrderedVerbs []string
+	// AllowedOrigins are the origins allowed to connect. It supports "*" and
+	// wildcard subdomains (ex. "https://*.example.com"). The origins without
+	// a port allow every port. When it is empty only the same origin is
+	// allowed.
+	AllowedOrigins []string
+	// ReadBufferSize is the size of the read buffer (defaults to 1024)
+	ReadBufferSize int
+	// WriteBufferSize is the size of the write buffer (defaults to 1024)
+	WriteBufferSize int
+	// DisableCompression disables the per message compression
+	DisableCompression bool
+	// CompressionLevel is the flate compression level (ex. flate.BestSpeed)
+	CompressionLevel int
+	// MaxMessageSize is the maximum size in bytes of a message read from a
+	// socket (zero means no limit). The socket is closed with close code 1009
+	// when a message exceeds it and *MessageSizeError is reported to OnError.
+	// Use middleware.MaxBodySize to limit the body size of a route.
+	MaxMessageSize int64
+	// HandshakeTimeout is the time allowed to complete the handshake
+	HandshakeTimeout time.Duration
+	// Subprotocols are the supported subprotocols in addition to the
+	// registered codecs. The other requested subprotocols are not selected.
+	Subprotocols []string
+}
+
 // Mux is a simple WebSocket route multiplexer
 //
 // Mux is designed to be fast, minimal and offer a powerful API for building
@@ -20,14 +84,26 @@ type WebSockets map[string]ResponseWriter
 // into many smaller parts composed of middlewares and end handlers.
 type Mux struct {
 	rw *sync.RWMutex
+	// options of this mux
+	options *MuxOptions
 	// sockets is the list of all available sockets
 	sockets WebSockets
+	// rooms groups the sockets for broadcasting
+	rooms *rooms
 	// The websocket upgrader
 	upgrader *websocket.Upgrader
 	// The handlers stack
 	handlers map[string]Handler
+	// The pattern routes ordered by precedence
+	patterns []*pattern
+	// notFoundHandler handles the unknown verbs
+	notFoundHandler HandlerFunc
+	// methodNotAllowedHandler handles the unknown verbs of a namespace
+	methodNotAllowedHandler HandlerFunc
 	// The middleware stack
 	middlewares []MiddlewareFunc
+	// authenticateFn authenticates the handshake requests
+	authenticateFn AuthenticateFunc
 	// onConnectFn called after each new connection
 	onConnectFn OnConnectFunc
 	// onDisconnectFn called after each connection is closed
@@ -36,45 +112,100 @@ type Mux struct {
 	onErrorFn OnErrorFunc
 	// stopChan stops all sockets
 	stopChan chan struct{}
+	// inflight tracks the requests being served
+	inflight *sync.WaitGroup
+	// shutdown is true when the mux does not accept new work
+	shutdown bool
+	// workers limits the concurrent requests across sockets
+	workers *WorkerPool
+	// parent is the router that mounts this router
+	parent *Mux
+	// connects is the number of connected sockets
+	connects uint64
+	// disconnects is the number of disconnected sockets
+	disconnects uint64
 }
 
 // NewMux creates an instance of *Mux
 func NewMux() *Mux {
+	return NewMuxWithOptions(&MuxOptions{})
+}
+
+// NewMuxWithOptions creates an instance of *Mux configured by the provided
+// options
+func NewMuxWithOptions(options *MuxOptions) *Mux {
+	if options == nil {
+		options = &MuxOptions{}
+	}
+
+	var workers *WorkerPool
+	if options.WorkerPoolSize > 0 {
+		workers = NewWorkerPool(options.WorkerPoolSize)
+	}
+
 	return &Mux{
 		rw:          &sync.RWMutex{},
+		options:     options,
 		handlers:    map[string]Handler{},
 		sockets:     WebSockets{},
+		rooms:       newRooms(),
 		middlewares: []MiddlewareFunc{},
 		upgrader: &websocket.Upgrader{
-			CheckOrigin:       func(r *http.Request) bool { return true },
-			EnableCompression: true,
-			ReadBufferSize:    1024,
-			WriteBufferSize:   1024,
+			CheckOrigin:       originChecker(options.AllowedOrigins),
+			EnableCompression: !options.DisableCompression,
+			ReadBufferSize:    bufferSize(options.ReadBufferSize),
+			WriteBufferSize:   bufferSize(options.WriteBufferSize),
+			HandshakeTimeout:  options.HandshakeTimeout,
 		},
 		stopChan: make(chan struct{}),
+		inflight: &sync.WaitGroup{},
+		workers:  workers,
 	}
 }
 
 // ServeRPC is the single method of the pho.Handler interface that makes
 // Mux nestable in order to build hierarchies
+//
+// The verb of the request is routed in the following order:
+//
+//  1. A handler registered for the exact verb (ex. "document:open")
+//  2. The most specific pattern route or mounted namespace. The segments
+//     are compared from left to right. A static segment precedes a
+//     parameter (ex. "{id}") that precedes a wildcard (ex. "*"). A mounted
+//     namespace (ex. "document") is compared as "document:*" and precedes
+//     a pattern route of the same shape. Pattern routes of the same shape
+//     are matched in the order of registration.
+//  3. The catch-all pattern route "*"
+//
+// When the verb cannot be routed, the request is handled by the NotFound
+// handler of the closest router that has one.
 func (m *Mux) ServeRPC(w SocketWriter, r *Request) {
-	attrb := strings.SplitN(strings.ToLower(r.Type), ":", 2)
-	verb := attrb[0]
+	rctx := GetRouteContext(r.Context())
+	if rctx == nil {
+		rctx = &RouteContext{Verb: r.Type, Params: map[string]string{}}
+		r = r.WithContext(context.WithValue(r.Context(), routeCtxKey, rctx))
+	}
+
+	if m.notFoundHandler != nil {
+		rctx.notFound = m.notFoundHandler
+	}
 
-	if len(attrb) > 1 {
-		r.Type = attrb[1]
+	if m.methodNotAllowedHandler != nil {
+		rctx.methodNotAllowed = m.methodNotAllowedHandler
 	}
 
-	if verb == ErrorType {
+	parts := strings.Split(r.Type, ":")
+
+	if strings.ToLower(parts[0]) == ErrorType {
 		err := fmt.Errorf("%s", string(r.Body))
 		m.handleError(err)
 	}
 
-	handler, ok := m.handlers[verb]
+	handler, ok := m.route(r, rctx, parts)
 	if !ok {
-		err := w.WriteError(fmt.Errorf("The route %q does not exist", r.Type), http.StatusNotFound)
-		m.handleError(err)
-		return
+		// the handlers of unknown verbs receive the original verb
+		r.Type = rctx.Verb
+		handler = m.notFound(rctx)
 	}
 
 	m.prepareWriter(w)
@@ -82,12 +213,88 @@ func (m *Mux) ServeRPC(w SocketWriter, r *Request) {
 	handler.ServeRPC(w, r)
 }
 
+// route finds the handler of the request verb
+func (m *Mux) route(r *Request, rctx *RouteContext, parts []string) (Handler, bool) {
+	if handler, ok := m.handlers[strings.ToLower(r.Type)]; ok {
+		rctx.RoutePatterns = append(rctx.RoutePatterns, strings.ToLower(r.Type))
+		return handler, true
+	}
+
+	var (
+		route  *pattern
+		params map[string]string
+		ok     bool
+	)
+
+	for _, candidate := range m.patterns {
+		if params, ok = candidate.match(parts); ok {
+			route = candidate
+			break
+		}
+	}
+
+	if len(parts) > 1 {
+		handler, ok := m.handlers[strings.ToLower(parts[0])]
+
+		if ok && (route == nil || precedence(route.segments, namespace) <= 0) {
+			r.Type = strings.Join(parts[1:], ":")
+			rctx.mounted = true
+			rctx.RoutePatterns = append(rctx.RoutePatterns, strings.ToLower(parts[0])+":*")
+			return handler, true
+		}
+	}
+
+	if route == nil {
+		return nil, false
+	}
+
+	for key, value := range params {
+		rctx.Params[key] = value
+	}
+
+	rctx.RoutePatterns = append(rctx.RoutePatterns, route.verb)
+	return route.handler, true
+}
+
+// notFound returns the handler of a verb that cannot be routed
+func (m *Mux) notFound(rctx *RouteContext) HandlerFunc {
+	if rctx.mounted && rctx.methodNotAllowed != nil {
+		return rctx.methodNotAllowed
+	}
+
+	if rctx.notFound != nil {
+		return rctx.notFound
+	}
+
+	return func(w SocketWriter, r *Request) {
+		err := w.WriteError(fmt.Errorf("The route %q does not exist", r.Type), http.StatusNotFound)
+		m.handleError(err)
+	}
+}
+
 // ServeHTTP is the single method of the http.Handler interface that makes
 // Mux interoperable with the standard library.
 func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.rw.RLock()
+	shutdown := m.shutdown
+	m.rw.RUnlock()
+
+	if shutdown {
+		http.Error(w, ErrServerClosed.Error(), http.StatusServiceUnavailable)
+		return
+	}
+
+	principal, ok := m.authenticate(w, r)
+	if !ok {
+		return
+	}
+
 	header := http.Header{}
-	if protocols := websocket.Subprotocols(r); len(protocols) > 0 {
-		header = http.Header{"Sec-Websocket-Protocol": {protocols[0]}}
+	codec := JSONCodec
+
+	if protocol, selected, ok := m.subprotocol(websocket.Subprotocols(r)); ok {
+		codec = selected
+		header = http.Header{"Sec-Websocket-Protocol": {protocol}}
 	}
 
 	conn, err := m.upgrader.Upgrade(w, r, header)
@@ -97,16 +304,34 @@ func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if m.options.CompressionLevel != 0 {
+		if err := conn.SetCompressionLevel(m.options.CompressionLevel); err != nil {
+			m.handleError(err)
+		}
+	}
+
 	socket, err := NewSocket(&SocketOptions{
-		UserAgent:    r.UserAgent(),
-		TLS:          r.TLS,
-		Host:         r.Host,
-		RequestURI:   r.RequestURI,
-		Conn:         conn,
-		OnDisconnect: m.removeSocket,
-		OnError:      m.handleError,
-		ServeRPC:     m.ServeRPC,
-		StopChan:     m.stopChan,
+		UserAgent:      r.UserAgent(),
+		TLS:            r.TLS,
+		Host:           r.Host,
+		RequestURI:     r.RequestURI,
+		Conn:           conn,
+		OnDisconnect:   m.removeSocket,
+		OnError:        m.handleError,
+		ServeRPC:       m.serveSocket,
+		StopChan:       m.stopChan,
+		QueueSize:      m.options.WriteQueueSize,
+		QueuePolicy:    m.options.WriteQueuePolicy,
+		PingInterval:   m.options.PingInterval,
+		PongTimeout:    m.options.PongTimeout,
+		ReadDeadline:   m.options.ReadDeadline,
+		CloseTimeout:   m.options.CloseTimeout,
+		Codec:          codec,
+		Concurrency:    m.options.MaxConcurrency,
+		Workers:        m.workers,
+		Ordered:        orderedVerbs(m.options.OrderedVerbs),
+		Principal:      principal,
+		MaxMessageSize: m.options.MaxMessageSize,
 	})
 
 	if err != nil {
@@ -118,14 +343,27 @@ func (m *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	m.rw.Lock()
 	m.sockets[socket.SocketID()] = socket
+	m.connects++
+	shutdown = m.shutdown
 	m.rw.Unlock()
 
-	go socket.run()
+	socket.start()
+
+	// the mux has been shut down during the handshake
+	if shutdown {
+		go socket.run()
+		socket.shutdown(m.closeCode(), m.options.CloseReason)
+		return
+	}
 
 	if m.onConnectFn != nil {
 		m.prepareWriter(socket)
 		m.onConnectFn(socket, r)
 	}
+
+	// the requests are read once OnConnect has written the metadata, which
+	// is shared with the handlers
+	go socket.run()
 }
 
 // Use appends one of more middlewares onto the Router stack.
@@ -134,8 +372,56 @@ func (m *Mux) Use(middlewares ...MiddlewareFunc) {
 }
 
 // On registers a handler for particular type of request
+//
+// The verb can be a pattern that captures parameters (ex. "user:{id}:update")
+// or ends with a wildcard (ex. "document:*"). The captured parameters are
+// available through VerbParam. A single wildcard "*" catches all verbs.
 func (m *Mux) On(method string, handler HandlerFunc) {
-	m.handlers[method] = handler
+	m.Handle(method, handler)
+}
+
+// Handle registers a handler for particular type of request like On does.
+// It keeps the handler as is, so Walk provides the handlers created by
+// pho.Handle.
+func (m *Mux) Handle(method string, handler Handler) {
+	if !isPattern(method) {
+		m.handlers[method] = handler
+		return
+	}
+
+	route, err := newPattern(method, handler)
+	if err != nil {
+		m.handleError(err)
+		return
+	}
+
+	m.patterns = append(m.patterns, route)
+
+	sort.SliceStable(m.patterns, func(i, j int) bool {
+		return precedence(m.patterns[i].segments, m.patterns[j].segments) > 0
+	})
+}
+
+// NotFound registers a handler of the verbs that cannot be routed. The
+// handler is inherited by the mounted routers that do not have one.
+func (m *Mux) NotFound(handler HandlerFunc) {
+	m.notFoundHandler = handler
+}
+
+// MethodNotAllowed registers a handler of the verbs that match a mounted
+// namespace but cannot be routed by it (ex. "document:unknown" when
+// "document" is mounted). The handler is inherited by the mounted routers
+// that do not have one. Such verbs are handled by NotFound by default.
+func (m *Mux) MethodNotAllowed(handler HandlerFunc) {
+	m.methodNotAllowedHandler = handler
+}
+
+// Authenticate registers a hook that authenticates every handshake request
+// before it is upgraded. The principal is available to the requests of the
+// socket through GetPrincipal and SocketPrincipal. The errors of the rejected
+// handshakes are reported to OnError.
+func (m *Mux) Authenticate(fn AuthenticateFunc) {
+	m.authenticateFn = fn
 }
 
 // OnConnect register a callback function called on error
@@ -143,7 +429,8 @@ func (m *Mux) OnError(fn OnErrorFunc) {
 	m.onErrorFn = fn
 }
 
-// OnConnect register a callback function called on conection
+// OnConnect register a callback function called on conection. The socket
+// reads the requests once the callback returns.
 func (m *Mux) OnConnect(fn OnConnectFunc) {
 	m.onConnectFn = fn
 }
@@ -153,22 +440,18 @@ func (m *Mux) OnDisconnect(fn OnDisconnectFunc) {
 	m.onDisconnectFn = fn
 }
 
-// Mount attaches another http.Handler along the channel
+// Mount attaches another http.Handler along the channel. The namespaces of
+// the verb (ex. "org:project:doc") are mounted as subrouters that are
+// created on demand and reused by the next calls.
 func (m *Mux) Mount(method string, handler Handler) {
 	method = strings.ToLower(method)
 	attrb := strings.SplitN(method, ":", 2)
 
 	if len(attrb) == 2 {
-		var router *Mux
-		h, ok := m.handlers[attrb[0]]
-
-		if ok {
-			if router, ok = h.(*Mux); !ok {
-				m.handleError(fmt.Errorf("The router at %q does not support mounting", attrb[0]))
-				return
-			}
-		} else {
-			router = NewMux()
+		router, err := m.subrouter(attrb[0])
+		if err != nil {
+			m.handleError(err)
+			return
 		}
 
 		router.Mount(attrb[1], handler)
@@ -179,17 +462,135 @@ func (m *Mux) Mount(method string, handler Handler) {
 }
 
 // Route creates a new Mux with a fresh middleware stack and mounts it
-// along the `pattern` as a subrouter. Effectively, this is a short-hand
-// call to Mount.
+// along the `pattern` as a subrouter. Every namespace of the verb (ex.
+// "org:project") gets its own subrouter. The subrouters that are already
+// mounted are reused, so the routes can be registered in several calls.
+// It panics when a namespace is taken by a handler that is not a router,
+// because the routes registered on it would never be served.
 func (m *Mux) Route(verb string, fn RouterFunc) Router {
-	mux := NewRouter()
+	router := m
+
+	for _, namespace := range strings.Split(strings.ToLower(verb), ":") {
+		next, err := router.subrouter(namespace)
+		if err != nil {
+			panic(err)
+		}
+		router = next
+	}
+
+	if fn != nil {
+		fn(router)
+	}
+
+	return router
+}
+
+// With creates an inline router that applies the provided middlewares to
+// the handlers registered on it. The handlers are registered on this mux
+// without a new namespace.
+func (m *Mux) With(middlewares ...MiddlewareFunc) Router {
+	return &inlineRouter{mux: m, middlewares: middlewares}
+}
+
+// Group creates an inline router with a fresh middleware stack and passes it
+// to fn. The handlers are registered on this mux without a new namespace.
+func (m *Mux) Group(fn RouterFunc) Router {
+	router := m.With()
 
 	if fn != nil {
-		fn(mux)
+		fn(router)
+	}
+
+	return router
+}
+
+// subrouter returns the router mounted at the namespace. It creates and
+// mounts a new one when the namespace is free.
+func (m *Mux) subrouter(namespace string) (*Mux, error) {
+	handler, ok := m.handlers[namespace]
+	if !ok {
+		router := m.child()
+		m.handlers[namespace] = router
+		return router, nil
+	}
+
+	if inline, ok := handler.(*inlineHandler); ok {
+		handler = inline.handler
+	}
+
+	if router, ok := handler.(*Mux); ok {
+		return router, nil
+	}
+
+	return nil, fmt.Errorf("The router at %q does not support mounting", namespace)
+}
+
+// child creates a router that reports its errors to this mux
+func (m *Mux) child() *Mux {
+	router := NewMux()
+	router.parent = m
+	return router
+}
+
+// root returns the router that serves the sockets
+func (m *Mux) root() *Mux {
+	for m.parent != nil {
+		m = m.parent
+	}
+	return m
+}
+
+// Shutdown gracefully shuts down the mux without interrupting the requests
+// being served. Shutdown stops accepting new connections and requests, sends
+// a close frame to every socket and waits for the in-flight requests to
+// finish and the sockets to disconnect. If the context expires before that,
+// Shutdown closes the remaining connections and returns the context's error.
+func (m *Mux) Shutdown(ctx context.Context) error {
+	m.rw.Lock()
+	m.shutdown = true
+	sockets := Copy(m.sockets)
+	m.rw.Unlock()
+
+	for _, socket := range sockets {
+		socket.(*Socket).shutdown(m.closeCode(), m.options.CloseReason)
 	}
 
-	m.Mount(verb, mux)
-	return mux
+	done := make(chan struct{})
+
+	go func() {
+		m.inflight.Wait()
+		close(done)
+	}()
+
+	ticker := time.NewTicker(shutdownPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			m.rw.RLock()
+			sockets = Copy(m.sockets)
+			m.rw.RUnlock()
+
+			for _, socket := range sockets {
+				socket.(*Socket).close()
+			}
+
+			return ctx.Err()
+		case <-ticker.C:
+			m.rw.RLock()
+			idle := len(m.sockets) == 0
+			m.rw.RUnlock()
+
+			select {
+			case <-done:
+				if idle {
+					return nil
+				}
+			default:
+			}
+		}
+	}
 }
 
 // Close stops all connections
@@ -198,10 +599,72 @@ func (m *Mux) Close() {
 	m.stopChan = make(chan struct{})
 }
 
-func (m *Mux) prepareWriter(w SocketWriter) {
+// subprotocol selects the subprotocol of the connection and its codec
+func (m *Mux) subprotocol(protocols []string) (string, Codec, bool) {
+	for _, name := range protocols {
+		if codec, ok := LookupCodec(name); ok {
+			return name, codec, true
+		}
+	}
+
+	for _, name := range protocols {
+		for _, supported := range m.options.Subprotocols {
+			if name == supported {
+				return name, JSONCodec, true
+			}
+		}
+	}
+
+	return "", JSONCodec, false
+}
+
+func (m *Mux) closeCode() int {
+	if m.options.CloseCode == 0 {
+		return websocket.CloseGoingAway
+	}
+	return m.options.CloseCode
+}
+
+// bufferSize returns the size of the upgrader buffer
+func bufferSize(size int) int {
+	if size <= 0 {
+		return 1024
+	}
+	return size
+}
+
+// serveSocket serves the requests of the sockets until the mux is shut down
+func (m *Mux) serveSocket(w SocketWriter, r *Request) {
 	m.rw.RLock()
-	w.Metadata()[MetadataSocketKey] = Copy(m.sockets)
+	if m.shutdown {
+		m.rw.RUnlock()
+		return
+	}
+	m.inflight.Add(1)
 	m.rw.RUnlock()
+
+	defer m.inflight.Done()
+
+	if m.options.Recover {
+		defer m.recoverPanic(w)
+	}
+
+	m.ServeRPC(w, r)
+}
+
+func (m *Mux) prepareWriter(w SocketWriter) {
+	root := m.root()
+
+	root.rw.RLock()
+	sockets := Copy(root.sockets)
+	root.rw.RUnlock()
+
+	// the writer of the request replaces its socket
+	if _, ok := sockets[w.SocketID()]; ok {
+		sockets[w.SocketID()] = w
+	}
+
+	w.Metadata()[MetadataSocketKey] = sockets
 }
 
 func (m *Mux) handleError(err error) {
@@ -211,12 +674,16 @@ func (m *Mux) handleError(err error) {
 
 	if m.onErrorFn != nil {
 		m.onErrorFn(err)
+	} else if m.parent != nil {
+		m.parent.handleError(err)
 	}
 }
 
 func (m *Mux) removeSocket(w SocketWriter) {
 	m.rw.Lock()
 	delete(m.sockets, w.SocketID())
+	m.disconnects++
+	m.rooms.leaveAll(w.SocketID())
 	m.rw.Unlock()
 
 	if m.onDisconnectFn != nil {
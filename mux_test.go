@@ -1,9 +1,15 @@
 package pho_test
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
 
 	"github.com/svett/pho"
 
@@ -273,6 +279,216 @@ var _ = Describe("Mux", func() {
 		})
 	})
 
+	Context("when a deep namespace is mount", func() {
+		echo := func(w pho.SocketWriter, r *pho.Request) {
+			w.Write(r.Type, 200, r.Body)
+		}
+
+		It("creates the missing subrouters", func() {
+			router.Mount("org:project:doc:update", pho.HandlerFunc(echo))
+
+			client, err := pho.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+			Expect(err).To(BeNil())
+			defer client.Close()
+
+			response, err := client.Call(context.Background(), "org:project:doc:update", []byte(`"Hi"`))
+			Expect(err).To(BeNil())
+			Expect(response.Type).To(Equal("update"))
+			Expect(string(response.Payload)).To(Equal(`"Hi"`))
+		})
+
+		It("reuses the subrouters", func() {
+			router.Route("org:project", func(r pho.Router) {
+				r.On("create", echo)
+			})
+
+			router.Mount("org:project:doc:update", pho.HandlerFunc(echo))
+
+			router.Route("org", func(r pho.Router) {
+				r.Route("project", func(r pho.Router) {
+					r.On("delete", echo)
+				})
+			})
+
+			client, err := pho.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+			Expect(err).To(BeNil())
+			defer client.Close()
+
+			for _, verb := range []string{"org:project:create", "org:project:doc:update", "org:project:delete"} {
+				_, err = client.Call(context.Background(), verb, []byte(`"Hi"`))
+				Expect(err).To(BeNil())
+			}
+		})
+
+		It("applies the middlewares of every subrouter", func() {
+			var trace []string
+
+			trail := func(name string) pho.MiddlewareFunc {
+				return func(next pho.Handler) pho.Handler {
+					return pho.HandlerFunc(func(w pho.SocketWriter, r *pho.Request) {
+						trace = append(trace, name)
+						next.ServeRPC(w, r)
+					})
+				}
+			}
+
+			router.Use(trail("root"))
+			router.Route("org", func(r pho.Router) {
+				r.Use(trail("org"))
+				r.Route("project", func(r pho.Router) {
+					r.Use(trail("project"))
+					r.On("update", echo)
+				})
+			})
+
+			client, err := pho.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+			Expect(err).To(BeNil())
+			defer client.Close()
+
+			_, err = client.Call(context.Background(), "org:project:update", []byte(`"Hi"`))
+			Expect(err).To(BeNil())
+			Expect(trace).To(Equal([]string{"root", "org", "project"}))
+		})
+
+		It("reports the errors of the subrouters to the root router", func() {
+			errs := make(chan error, 1)
+			router.OnError(func(err error) {
+				errs <- err
+			})
+
+			router.Route("org:project", func(r pho.Router) {
+				r.On("{id", echo)
+			})
+
+			Eventually(errs).Should(Receive(MatchError(`The segment "{id" of "{id" is invalid`)))
+		})
+
+		It("reports the namespaces that cannot be mounted", func() {
+			errs := make(chan error, 1)
+			router.OnError(func(err error) {
+				errs <- err
+			})
+
+			router.On("org", echo)
+			router.Mount("org:project", pho.HandlerFunc(echo))
+
+			Eventually(errs).Should(Receive(MatchError(`The router at "org" does not support mounting`)))
+		})
+
+		It("panics when a route namespace is taken by a handler", func() {
+			router.On("org", echo)
+
+			Expect(func() {
+				router.Route("org:project", func(r pho.Router) {
+					r.On("create", echo)
+				})
+			}).To(PanicWith(MatchError(`The router at "org" does not support mounting`)))
+		})
+
+		It("provides the sockets of the root router", func() {
+			sockets := make(chan int, 1)
+
+			router.Route("org:project", func(r pho.Router) {
+				r.On("list", func(w pho.SocketWriter, r *pho.Request) {
+					sockets <- len(pho.Sockets(w))
+					w.Write(r.Type, 200, r.Body)
+				})
+			})
+
+			client, err := pho.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+			Expect(err).To(BeNil())
+			defer client.Close()
+
+			_, err = client.Call(context.Background(), "org:project:list", []byte(`""`))
+			Expect(err).To(BeNil())
+			Eventually(sockets).Should(Receive(Equal(1)))
+		})
+	})
+
+	Context("when the routes are grouped", func() {
+		var trace []string
+
+		trail := func(name string) pho.MiddlewareFunc {
+			return func(next pho.Handler) pho.Handler {
+				return pho.HandlerFunc(func(w pho.SocketWriter, r *pho.Request) {
+					trace = append(trace, name)
+					next.ServeRPC(w, r)
+				})
+			}
+		}
+
+		echo := func(w pho.SocketWriter, r *pho.Request) {
+			trace = append(trace, r.Type)
+			w.Write(r.Type, 200, r.Body)
+		}
+
+		BeforeEach(func() {
+			trace = nil
+		})
+
+		call := func(verb string) {
+			client, err := pho.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+			Expect(err).To(BeNil())
+			defer client.Close()
+
+			_, err = client.Call(context.Background(), verb, []byte(`""`))
+			Expect(err).To(BeNil())
+		}
+
+		It("applies the inline middlewares with With", func() {
+			router.Use(trail("root"))
+			router.With(trail("inline")).On("secure", echo)
+			router.On("public", echo)
+
+			call("secure")
+			Expect(trace).To(Equal([]string{"root", "inline", "secure"}))
+
+			trace = nil
+
+			call("public")
+			Expect(trace).To(Equal([]string{"root", "public"}))
+		})
+
+		It("applies the group middlewares without a new namespace", func() {
+			router.Group(func(r pho.Router) {
+				r.Use(trail("group"))
+				r.On("secure", echo)
+				r.With(trail("inline")).On("admin", echo)
+			})
+			router.On("public", echo)
+
+			call("secure")
+			Expect(trace).To(Equal([]string{"group", "secure"}))
+
+			trace = nil
+
+			call("admin")
+			Expect(trace).To(Equal([]string{"group", "inline", "admin"}))
+
+			trace = nil
+
+			call("public")
+			Expect(trace).To(Equal([]string{"public"}))
+		})
+
+		It("applies the inline middlewares to the sub routes", func() {
+			router.With(trail("inline")).Route("org:project", func(r pho.Router) {
+				r.On("update", echo)
+			})
+			router.Route("org:project", func(r pho.Router) {
+				r.On("delete", echo)
+			})
+
+			call("org:project:update")
+			Expect(trace).To(Equal([]string{"inline", "update"}))
+
+			trace = nil
+
+			call("org:project:delete")
+			Expect(trace).To(Equal([]string{"delete"}))
+		})
+	})
+
 	Context("when middleware is registered", func() {
 		It("calls it before handling the request", func() {
 			cnt := 0
@@ -332,4 +548,594 @@ var _ = Describe("Mux", func() {
 			Eventually(func() int { return cnt }).Should(Equal(1))
 		})
 	})
+
+	Context("when the heartbeat is configured", func() {
+		BeforeEach(func() {
+			router.Close()
+			server.Close()
+
+			router = pho.NewMuxWithOptions(&pho.MuxOptions{
+				PingInterval: 20 * time.Millisecond,
+				PongTimeout:  50 * time.Millisecond,
+				ReadDeadline: 100 * time.Millisecond,
+			})
+			server = httptest.NewServer(router)
+		})
+
+		It("keeps the healthy clients connected", func() {
+			cnt := 0
+			router.OnDisconnect(func(w pho.SocketWriter) {
+				cnt++
+			})
+
+			client, err := pho.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+			Expect(err).To(BeNil())
+			defer client.Close()
+
+			Consistently(func() int { return cnt }, 500*time.Millisecond).Should(Equal(0))
+		})
+
+		It("disconnects the clients that do not answer", func() {
+			reasons := make(chan error, 1)
+			router.OnDisconnect(func(w pho.SocketWriter) {
+				reasons <- pho.DisconnectReason(w)
+			})
+
+			url := fmt.Sprintf("ws://%s", server.Listener.Addr().String())
+			conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+			Expect(err).To(BeNil())
+			defer conn.Close()
+
+			Eventually(reasons).Should(Receive(Equal(pho.ErrHeartbeatTimeout)))
+		})
+	})
+
+	Context("when the mux is shut down", func() {
+		It("waits for the requests being served", func() {
+			started := make(chan struct{})
+			served := false
+
+			router.On("slow", func(w pho.SocketWriter, r *pho.Request) {
+				close(started)
+				time.Sleep(200 * time.Millisecond)
+				served = true
+			})
+
+			client, err := pho.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+			Expect(err).To(BeNil())
+			defer client.Close()
+
+			reasons := make(chan error, 1)
+			client.OnDisconnect(func(err error) {
+				reasons <- err
+			})
+
+			Expect(client.Write("slow", []byte(`""`))).To(Succeed())
+			Eventually(started).Should(BeClosed())
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			Expect(router.Shutdown(ctx)).To(Succeed())
+			Expect(served).To(BeTrue())
+
+			var reason error
+			Eventually(reasons).Should(Receive(&reason))
+			Expect(websocket.IsCloseError(reason, websocket.CloseGoingAway)).To(BeTrue())
+
+			_, err = pho.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+			Expect(err).To(MatchError("websocket: bad handshake"))
+		})
+
+		Context("when the context expires", func() {
+			It("closes the remaining connections", func() {
+				cnt := 0
+				router.OnDisconnect(func(w pho.SocketWriter) {
+					defer GinkgoRecover()
+					cnt++
+					Expect(pho.DisconnectReason(w)).To(Equal(pho.ErrServerClosed))
+				})
+
+				// the connection does not read and cannot answer the close frame
+				url := fmt.Sprintf("ws://%s", server.Listener.Addr().String())
+				conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+				Expect(err).To(BeNil())
+				defer conn.Close()
+
+				ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+				defer cancel()
+
+				Expect(router.Shutdown(ctx)).To(Equal(context.DeadlineExceeded))
+				Eventually(func() int { return cnt }).Should(Equal(1))
+			})
+
+			It("disconnects the sockets blocked by a request", func() {
+				var errs, disconnects int32
+
+				router.OnError(func(err error) {
+					atomic.AddInt32(&errs, 1)
+				})
+
+				router.OnDisconnect(func(w pho.SocketWriter) {
+					atomic.AddInt32(&disconnects, 1)
+				})
+
+				started := make(chan struct{}, 3)
+				release := make(chan struct{})
+
+				router.On("wait", func(w pho.SocketWriter, r *pho.Request) {
+					started <- struct{}{}
+					<-release
+				})
+
+				url := fmt.Sprintf("ws://%s", server.Listener.Addr().String())
+				conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+				Expect(err).To(BeNil())
+				defer conn.Close()
+
+				// the third request blocks the read loop
+				for i := 0; i < 3; i++ {
+					Expect(conn.WriteMessage(websocket.TextMessage, []byte(`{"Type":"wait","body":""}`))).To(Succeed())
+				}
+
+				Eventually(started).Should(Receive())
+				time.Sleep(50 * time.Millisecond)
+
+				ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+				defer cancel()
+
+				Expect(router.Shutdown(ctx)).To(Equal(context.DeadlineExceeded))
+				close(release)
+
+				Eventually(func() int32 { return atomic.LoadInt32(&disconnects) }).Should(Equal(int32(1)))
+				Expect(router.Stats().ActiveSockets).To(BeZero())
+				Expect(atomic.LoadInt32(&errs)).To(BeNumerically("<", 10))
+			})
+		})
+	})
+
+	Context("when the NotFound handler is registered", func() {
+		var (
+			client *pho.Client
+			mu     *sync.Mutex
+			calls  []string
+		)
+
+		record := func(name string) pho.HandlerFunc {
+			return func(w pho.SocketWriter, r *pho.Request) {
+				mu.Lock()
+				defer mu.Unlock()
+				calls = append(calls, name+" "+r.Type)
+			}
+		}
+
+		recorded := func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return calls
+		}
+
+		BeforeEach(func() {
+			mu = &sync.Mutex{}
+			calls = []string{}
+
+			router.NotFound(record("not-found"))
+			router.Route("documents", func(r pho.Router) {
+				r.On("open", record("open"))
+				r.Route("blocks", func(r pho.Router) {
+					r.On("close", record("close"))
+				})
+			})
+
+			var err error
+			client, err = pho.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+			Expect(err).To(BeNil())
+		})
+
+		AfterEach(func() {
+			client.Close()
+		})
+
+		It("handles the unknown verbs", func() {
+			Expect(client.Write("unknown", []byte(`""`))).To(Succeed())
+			Eventually(recorded).Should(ConsistOf("not-found unknown"))
+		})
+
+		It("is inherited by the sub routers with the original verb", func() {
+			Expect(client.Write("documents:blocks:open", []byte(`""`))).To(Succeed())
+			Eventually(recorded).Should(ConsistOf("not-found documents:blocks:open"))
+		})
+
+		Context("when the sub router has its own handler", func() {
+			It("handles the unknown verbs of the sub router", func() {
+				router.Route("users", func(r pho.Router) {
+					r.NotFound(record("users-not-found"))
+				})
+
+				Expect(client.Write("users:delete", []byte(`""`))).To(Succeed())
+				Eventually(recorded).Should(ConsistOf("users-not-found users:delete"))
+			})
+		})
+
+		Context("when the MethodNotAllowed handler is registered", func() {
+			It("handles the unknown verbs of the mounted namespaces", func() {
+				router.MethodNotAllowed(record("not-allowed"))
+
+				Expect(client.Write("documents:delete", []byte(`""`))).To(Succeed())
+				Eventually(recorded).Should(ConsistOf("not-allowed documents:delete"))
+
+				Expect(client.Write("unknown", []byte(`""`))).To(Succeed())
+				Eventually(recorded).Should(ContainElement("not-found unknown"))
+			})
+		})
+	})
+
+	Context("when the nested path is not found", func() {
+		It("returns an error with the original verb", func() {
+			router.Route("documents", func(r pho.Router) {})
+
+			client, err := pho.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+			Expect(err).To(BeNil())
+			defer client.Close()
+
+			_, err = client.Call(context.Background(), "documents:delete", []byte(`"Hi"`))
+			Expect(err).To(MatchError(`The route "documents:delete" does not exist`))
+		})
+	})
+
+	Context("when the recover option is enabled", func() {
+		BeforeEach(func() {
+			router.Close()
+			server.Close()
+
+			router = pho.NewMuxWithOptions(&pho.MuxOptions{Recover: true})
+			server = httptest.NewServer(router)
+		})
+
+		It("recovers from handler panics", func() {
+			router.On("panic", func(w pho.SocketWriter, r *pho.Request) {
+				panic("oh no!")
+			})
+
+			router.On("echo", func(w pho.SocketWriter, r *pho.Request) {
+				Expect(w.Write("echo", http.StatusOK, r.Body)).To(Succeed())
+			})
+
+			errs := make(chan error, 1)
+			router.OnError(func(err error) {
+				errs <- err
+			})
+
+			client, err := pho.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+			Expect(err).To(BeNil())
+			defer client.Close()
+
+			_, err = client.Call(context.Background(), "panic", []byte(`""`))
+			Expect(err).To(Equal(&pho.ResponseError{
+				StatusCode: http.StatusInternalServerError,
+				Message:    "panic: oh no!",
+			}))
+
+			var reported error
+			Eventually(errs).Should(Receive(&reported))
+			Expect(reported).To(BeAssignableToTypeOf(&pho.PanicError{}))
+			Expect(string(reported.(*pho.PanicError).Stack)).To(ContainSubstring("mux_test.go"))
+
+			response, err := client.Call(context.Background(), "echo", []byte(`"alive"`))
+			Expect(err).To(BeNil())
+			Expect(string(response.Payload)).To(Equal(`"alive"`))
+		})
+	})
+
+	Context("when the requests are served concurrently", func() {
+		var client *pho.Client
+
+		BeforeEach(func() {
+			router.Close()
+			server.Close()
+
+			router = pho.NewMuxWithOptions(&pho.MuxOptions{
+				MaxConcurrency: 4,
+				WorkerPoolSize: 8,
+				OrderedVerbs:   []string{"edit"},
+			})
+			server = httptest.NewServer(router)
+		})
+
+		JustBeforeEach(func() {
+			var err error
+			client, err = pho.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+			Expect(err).To(BeNil())
+		})
+
+		AfterEach(func() {
+			client.Close()
+		})
+
+		It("does not block the socket while a handler is running", func() {
+			release := make(chan struct{})
+
+			router.On("slow", func(w pho.SocketWriter, r *pho.Request) {
+				<-release
+				Expect(w.Write("slow", http.StatusOK, r.Body)).To(Succeed())
+			})
+
+			router.On("fast", func(w pho.SocketWriter, r *pho.Request) {
+				Expect(w.Write("fast", http.StatusOK, r.Body)).To(Succeed())
+			})
+
+			slow := make(chan *pho.Response, 1)
+			go func() {
+				defer GinkgoRecover()
+				response, err := client.Call(context.Background(), "slow", []byte(`"slow"`))
+				Expect(err).To(BeNil())
+				slow <- response
+			}()
+
+			response, err := client.Call(context.Background(), "fast", []byte(`"fast"`))
+			Expect(err).To(BeNil())
+			Expect(string(response.Payload)).To(Equal(`"fast"`))
+			Consistently(slow).ShouldNot(Receive())
+
+			close(release)
+			Eventually(slow).Should(Receive())
+		})
+
+		It("cancels the request when the call is cancelled", func() {
+			cancelled := make(chan error, 1)
+
+			router.On("wait", func(w pho.SocketWriter, r *pho.Request) {
+				<-r.Context().Done()
+				cancelled <- r.Context().Err()
+			})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			_, err := client.Call(ctx, "wait", []byte(`""`))
+			Expect(err).To(Equal(context.DeadlineExceeded))
+			Eventually(cancelled).Should(Receive(Equal(context.Canceled)))
+		})
+
+		It("serves the ordered verbs in the order of arrival", func() {
+			mu := sync.Mutex{}
+			edits := []string{}
+
+			router.On("edit", func(w pho.SocketWriter, r *pho.Request) {
+				if string(r.Body) == `"1"` {
+					time.Sleep(50 * time.Millisecond)
+				}
+
+				mu.Lock()
+				edits = append(edits, string(r.Body))
+				mu.Unlock()
+			})
+
+			for _, body := range []string{`"1"`, `"2"`, `"3"`} {
+				Expect(client.Write("edit", []byte(body))).To(Succeed())
+			}
+
+			Eventually(func() []string {
+				mu.Lock()
+				defer mu.Unlock()
+				return append([]string{}, edits...)
+			}).Should(Equal([]string{`"1"`, `"2"`, `"3"`}))
+		})
+	})
+
+	Context("when the requests are served one by one", func() {
+		var client *pho.Client
+
+		JustBeforeEach(func() {
+			var err error
+			client, err = pho.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+			Expect(err).To(BeNil())
+		})
+
+		AfterEach(func() {
+			client.Close()
+		})
+
+		It("cancels the request when the call is cancelled", func() {
+			cancelled := make(chan error, 1)
+
+			router.On("wait", func(w pho.SocketWriter, r *pho.Request) {
+				<-r.Context().Done()
+				cancelled <- r.Context().Err()
+			})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			_, err := client.Call(ctx, "wait", []byte(`""`))
+			Expect(err).To(Equal(context.DeadlineExceeded))
+			Eventually(cancelled).Should(Receive(Equal(context.Canceled)))
+		})
+
+		It("cancels the request when the client disconnects", func() {
+			started := make(chan struct{})
+			cancelled := make(chan error, 1)
+
+			router.On("wait", func(w pho.SocketWriter, r *pho.Request) {
+				close(started)
+				<-r.Context().Done()
+				cancelled <- r.Context().Err()
+			})
+
+			conn, _, err := websocket.DefaultDialer.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+			Expect(err).To(BeNil())
+
+			Expect(conn.WriteMessage(websocket.TextMessage, []byte(`{"Type":"wait","body":""}`))).To(Succeed())
+			Eventually(started).Should(BeClosed())
+
+			Expect(conn.Close()).To(Succeed())
+			Eventually(cancelled).Should(Receive(Equal(context.Canceled)))
+		})
+
+		It("serves the requests in the order of arrival", func() {
+			mu := sync.Mutex{}
+			requests := []string{}
+
+			router.On("edit", func(w pho.SocketWriter, r *pho.Request) {
+				if string(r.Body) == `"1"` {
+					time.Sleep(50 * time.Millisecond)
+				}
+
+				mu.Lock()
+				requests = append(requests, string(r.Body))
+				mu.Unlock()
+			})
+
+			for _, body := range []string{`"1"`, `"2"`, `"3"`} {
+				Expect(client.Write("edit", []byte(body))).To(Succeed())
+			}
+
+			Eventually(func() []string {
+				mu.Lock()
+				defer mu.Unlock()
+				return append([]string{}, requests...)
+			}).Should(Equal([]string{`"1"`, `"2"`, `"3"`}))
+		})
+	})
+
+	It("cancels the request context when the mux is closed", func() {
+		started := make(chan struct{})
+		cancelled := make(chan error, 1)
+
+		router.On("wait", func(w pho.SocketWriter, r *pho.Request) {
+			close(started)
+			<-r.Context().Done()
+			cancelled <- r.Context().Err()
+		})
+
+		client, err := pho.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+		Expect(err).To(BeNil())
+		defer client.Close()
+
+		Expect(client.Write("wait", []byte(`""`))).To(Succeed())
+		Eventually(started).Should(BeClosed())
+
+		router.Close()
+		Eventually(cancelled).Should(Receive(Equal(context.Canceled)))
+	})
+
+	Context("when the upgrader is configured", func() {
+		var url string
+
+		BeforeEach(func() {
+			router.Close()
+			server.Close()
+
+			router = pho.NewMuxWithOptions(&pho.MuxOptions{
+				AllowedOrigins: []string{"https://example.com", "https://*.example.org", "http://*.example.net:3000"},
+				Subprotocols:   []string{"chat"},
+				MaxMessageSize: 64,
+			})
+			server = httptest.NewServer(router)
+			url = fmt.Sprintf("ws://%s", server.Listener.Addr().String())
+		})
+
+		It("allows the configured origins", func() {
+			for _, origin := range []string{"https://example.com", "https://example.com:8443", "https://api.example.org", "https://api.example.org:8443", "HTTPS://API.EXAMPLE.ORG", "http://app.example.net:3000"} {
+				conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {origin}})
+				Expect(err).To(BeNil())
+				conn.Close()
+			}
+		})
+
+		It("rejects the other origins", func() {
+			for _, origin := range []string{"https://evil.com", "http://example.com", "https://example.org", "https://example.com.evil.com", "https://api.example.org.evil.com:8443", "http://app.example.net", "http://app.example.net:3001"} {
+				_, response, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {origin}})
+				Expect(err).To(Equal(websocket.ErrBadHandshake))
+				Expect(response.StatusCode).To(Equal(http.StatusForbidden))
+			}
+		})
+
+		It("selects the supported subprotocol", func() {
+			dialer := &websocket.Dialer{Subprotocols: []string{"unknown", "chat"}}
+			conn, _, err := dialer.Dial(url, nil)
+			Expect(err).To(BeNil())
+			defer conn.Close()
+			Expect(conn.Subprotocol()).To(Equal("chat"))
+
+			dialer = &websocket.Dialer{Subprotocols: []string{"unknown"}}
+			conn, _, err = dialer.Dial(url, nil)
+			Expect(err).To(BeNil())
+			defer conn.Close()
+			Expect(conn.Subprotocol()).To(BeEmpty())
+		})
+
+		It("limits the size of the messages", func() {
+			errs := make(chan error, 10)
+			router.OnError(func(err error) {
+				errs <- err
+			})
+
+			conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+			Expect(err).To(BeNil())
+			defer conn.Close()
+
+			Expect(conn.WriteMessage(websocket.TextMessage, make([]byte, 128))).To(Succeed())
+
+			_, _, err = conn.ReadMessage()
+			Expect(websocket.IsCloseError(err, websocket.CloseMessageTooBig)).To(BeTrue())
+
+			var reported error
+			Eventually(errs).Should(Receive(&reported))
+			Expect(reported).To(BeAssignableToTypeOf(&pho.MessageSizeError{}))
+			Expect(reported.(*pho.MessageSizeError).SocketID).NotTo(BeEmpty())
+			Expect(reported.(*pho.MessageSizeError).Limit).To(Equal(int64(64)))
+		})
+	})
+
+	Context("when no origins are allowed", func() {
+		It("allows only the same origin", func() {
+			url := fmt.Sprintf("ws://%s", server.Listener.Addr().String())
+
+			conn, _, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {"http://" + server.Listener.Addr().String()}})
+			Expect(err).To(BeNil())
+			conn.Close()
+
+			_, response, err := websocket.DefaultDialer.Dial(url, http.Header{"Origin": {"https://evil.com"}})
+			Expect(err).To(Equal(websocket.ErrBadHandshake))
+			Expect(response.StatusCode).To(Equal(http.StatusForbidden))
+		})
+	})
+
+	Context("when no subprotocols are configured", func() {
+		It("selects only the codecs", func() {
+			url := fmt.Sprintf("ws://%s", server.Listener.Addr().String())
+
+			dialer := &websocket.Dialer{Subprotocols: []string{"chat", "msgpack"}}
+			conn, _, err := dialer.Dial(url, nil)
+			Expect(err).To(BeNil())
+			defer conn.Close()
+			Expect(conn.Subprotocol()).To(Equal("msgpack"))
+
+			dialer = &websocket.Dialer{Subprotocols: []string{"chat"}}
+			conn, _, err = dialer.Dial(url, nil)
+			Expect(err).To(BeNil())
+			defer conn.Close()
+			Expect(conn.Subprotocol()).To(BeEmpty())
+		})
+	})
+
+	It("provides the statistics", func() {
+		router.On("wait", func(w pho.SocketWriter, r *pho.Request) {})
+
+		client, err := pho.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+		Expect(err).To(BeNil())
+
+		Eventually(router.Stats).Should(Equal(pho.MuxStats{
+			ActiveSockets: 1,
+			Connects:      1,
+		}))
+
+		client.Close()
+
+		Eventually(router.Stats).Should(Equal(pho.MuxStats{
+			Connects:    1,
+			Disconnects: 1,
+		}))
+	})
 })
@@ -0,0 +1,88 @@
+package pho
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// defaultPorts are the ports of the origins that do not have a port
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"ws":    "80",
+	"wss":   "443",
+}
+
+// originChecker returns the origin check of the upgrader. When no origins
+// are allowed, the origin must match the host of the request. An allowed
+// origin can be "*" that allows every origin, an exact origin (ex.
+// "https://example.com") or a wildcard subdomain (ex. "https://*.example.com"
+// or "*.example.com" for every scheme). An allowed origin without a port
+// allows every port of the host, while an allowed origin with a port (ex.
+// "http://localhost:3000") allows only that port.
+func originChecker(allowed []string) func(r *http.Request) bool {
+	if len(allowed) == 0 {
+		// the upgrader checks for the same origin
+		return nil
+	}
+
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return true
+		}
+
+		u, err := url.Parse(origin)
+		if err != nil {
+			return false
+		}
+
+		scheme := strings.ToLower(u.Scheme)
+
+		port := u.Port()
+		if port == "" {
+			port = defaultPorts[scheme]
+		}
+
+		for _, pattern := range allowed {
+			if matchOrigin(pattern, scheme, strings.ToLower(u.Hostname()), port) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// matchOrigin reports whether the origin matches the allowed origin pattern
+func matchOrigin(pattern, scheme, host, port string) bool {
+	pattern = strings.ToLower(pattern)
+
+	if pattern == "*" {
+		return true
+	}
+
+	if index := strings.Index(pattern, "://"); index >= 0 {
+		if pattern[:index] != scheme {
+			return false
+		}
+		pattern = pattern[index+3:]
+	}
+
+	if patternHost, patternPort, err := net.SplitHostPort(pattern); err == nil {
+		if patternPort != port {
+			return false
+		}
+		pattern = patternHost
+	}
+
+	pattern = strings.TrimSuffix(strings.TrimPrefix(pattern, "["), "]")
+
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(host, pattern[1:])
+	}
+
+	return pattern == host
+}
@@ -8,6 +8,10 @@ import (
 // ErrorType defines the type of error Response and Request
 const ErrorType = "error"
 
+// CancelType defines the type of Request that cancels the context of the
+// request with the same ID
+const CancelType = "cancel"
+
 //go:generate counterfeiter -o ./fakes/FakeResponseWriter.go . ResponseWriter
 //go:generate counterfeiter -o ./fakes/FakeSocketWriter.go . SocketWriter
 
@@ -95,6 +99,16 @@ type Router interface {
 	// The On-function adds callbacks by name of the event, that should be handled.
 	On(verb string, handle HandlerFunc)
 
+	// Handle registers a handler by name of the event like On does.
+	Handle(verb string, handler Handler)
+
+	// NotFound registers a handler of the verbs that cannot be routed.
+	NotFound(handle HandlerFunc)
+
+	// MethodNotAllowed registers a handler of the verbs that match a
+	// mounted namespace but cannot be routed by it.
+	MethodNotAllowed(handle HandlerFunc)
+
 	// On-Connect func register callback invoked on each error
 	OnError(fn OnErrorFunc)
 
@@ -112,6 +126,17 @@ type Router interface {
 	// call to Mount.
 	Route(verb string, fn RouterFunc) Router
 
+	// With creates an inline router that applies the middlewares to the
+	// handlers registered on it without a new namespace.
+	With(middlewares ...MiddlewareFunc) Router
+
+	// Group creates an inline router with a fresh middleware stack along
+	// the current namespace.
+	Group(fn RouterFunc) Router
+
+	// Routes lists the routes of the router (see Walk)
+	Routes
+
 	// Close stops all connections
 	Close()
 }
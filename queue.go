@@ -0,0 +1,28 @@
+package pho
+
+import "errors"
+
+var (
+	// ErrQueueFull is returned when the write queue of a socket is full
+	ErrQueueFull = errors.New("The write queue is full")
+
+	// ErrSocketClosed is returned when writing to a closed socket
+	ErrSocketClosed = errors.New("The socket is closed")
+)
+
+// DefaultWriteQueueSize is the number of messages buffered per socket
+const DefaultWriteQueueSize = 256
+
+// QueuePolicy determines what happens when the write queue of a socket is full
+type QueuePolicy int
+
+const (
+	// QueueBlock blocks the writer until the queue has room
+	QueueBlock QueuePolicy = iota
+	// QueueDropOldest drops the oldest queued message
+	QueueDropOldest
+	// QueueDropNewest drops the message being written
+	QueueDropNewest
+	// QueueDisconnect disconnects the slow consumer
+	QueueDisconnect
+)
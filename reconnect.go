@@ -0,0 +1,66 @@
+package pho
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// ReconnectPolicy controls how a Client restores a lost connection. The
+// delay between attempts grows exponentially from MinDelay by Factor up to
+// MaxDelay and each delay is randomized by Jitter.
+type ReconnectPolicy struct {
+	// MinDelay before the first attempt (defaults to 1 second)
+	MinDelay time.Duration
+	// MaxDelay between two attempts (defaults to 30 seconds)
+	MaxDelay time.Duration
+	// Factor multiplies the delay after every attempt (defaults to 2)
+	Factor float64
+	// Jitter is the fraction of the delay that is randomized (ex. 0.2)
+	Jitter float64
+	// MaxAttempts before the client gives up (zero means unlimited)
+	MaxAttempts int
+	// MaxElapsedTime before the client gives up (zero means unlimited)
+	MaxElapsedTime time.Duration
+}
+
+// delay returns the backoff delay before the provided attempt
+func (p *ReconnectPolicy) delay(attempt int) time.Duration {
+	var (
+		min    = p.MinDelay
+		max    = p.MaxDelay
+		factor = p.Factor
+	)
+
+	if min <= 0 {
+		min = time.Second
+	}
+
+	if max <= 0 {
+		max = 30 * time.Second
+	}
+
+	if factor < 1 {
+		factor = 2
+	}
+
+	delay := float64(min) * math.Pow(factor, float64(attempt-1))
+	if delay > float64(max) {
+		delay = float64(max)
+	}
+
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+
+	return time.Duration(delay)
+}
+
+// exhausted returns true when no more attempts are allowed
+func (p *ReconnectPolicy) exhausted(attempt int, elapsed time.Duration) bool {
+	if p.MaxAttempts > 0 && attempt > p.MaxAttempts {
+		return true
+	}
+
+	return p.MaxElapsedTime > 0 && elapsed >= p.MaxElapsedTime
+}
@@ -0,0 +1,29 @@
+package pho
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+)
+
+// PanicError is reported when a handler panics
+type PanicError struct {
+	// Value passed to panic
+	Value interface{}
+	// Stack trace of the goroutine that panicked
+	Stack []byte
+}
+
+// Error returns the error message
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// recoverPanic recovers from a handler panic and writes an internal server
+// error to the socket. It must be deferred.
+func (m *Mux) recoverPanic(w SocketWriter) {
+	if rvr := recover(); rvr != nil {
+		err := &PanicError{Value: rvr, Stack: debug.Stack()}
+		m.handleError(w.WriteError(err, http.StatusInternalServerError))
+	}
+}
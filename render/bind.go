@@ -0,0 +1,134 @@
+package render
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+
+	"github.com/svett/pho"
+)
+
+// Binder is implemented by the request bodies that need to be processed
+// after they are decoded (ex. to set defaults or to check the fields that
+// depend on each other).
+type Binder interface {
+	Bind(r *pho.Request) error
+}
+
+// BindError is written to the client when the request body cannot be bound
+type BindError struct {
+	// Message describes the error
+	Message string
+	// FieldErrors are the invalid fields
+	FieldErrors []pho.FieldError
+}
+
+// Error returns the error message
+func (e *BindError) Error() string {
+	return e.Message
+}
+
+// Fields returns the invalid fields
+func (e *BindError) Fields() []pho.FieldError {
+	return e.FieldErrors
+}
+
+// validate validates the struct tags of the request bodies. The fields are
+// named after their json tags.
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+
+		switch name {
+		case "-":
+			return ""
+		case "":
+			return field.Name
+		default:
+			return name
+		}
+	})
+
+	return v
+}
+
+// Decode decodes the request body into v with the codec of the socket
+func Decode(w pho.SocketWriter, r *pho.Request, v interface{}) error {
+	return pho.SocketCodec(w).Unmarshal(r.Body, v)
+}
+
+// Bind decodes the request body into v, calls its Bind method if it is a
+// Binder and validates its struct tags (ex. `validate:"required"`). On
+// failure it writes a 400 Bad Request error when the body cannot be decoded
+// or a 422 Unprocessable Entity error that lists the invalid fields, and
+// returns the error.
+func Bind(w pho.SocketWriter, r *pho.Request, v interface{}) error {
+	if err := Decode(w, r, v); err != nil {
+		berr := &BindError{Message: fmt.Sprintf("The request body is malformed: %v", err)}
+		w.WriteError(berr, http.StatusBadRequest)
+		return berr
+	}
+
+	if binder, ok := v.(Binder); ok {
+		if err := binder.Bind(r); err != nil {
+			w.WriteError(err, http.StatusUnprocessableEntity)
+			return err
+		}
+	}
+
+	if err := validateStruct(v); err != nil {
+		w.WriteError(err, http.StatusUnprocessableEntity)
+		return err
+	}
+
+	return nil
+}
+
+// validateStruct validates the struct tags of v
+func validateStruct(v interface{}) error {
+	value := reflect.ValueOf(v)
+	for value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	err := validate.Struct(v)
+
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+
+	berr := &BindError{Message: "The request body is invalid"}
+
+	for _, verr := range verrs {
+		// the namespace starts with the name of the struct
+		field := verr.Namespace()
+		if index := strings.Index(field, "."); index >= 0 {
+			field = field[index+1:]
+		}
+
+		rule := verr.Tag()
+		if verr.Param() != "" {
+			rule = fmt.Sprintf("%s=%s", rule, verr.Param())
+		}
+
+		berr.FieldErrors = append(berr.FieldErrors, pho.FieldError{
+			Field:   field,
+			Message: fmt.Sprintf("The value does not satisfy %q", rule),
+		})
+	}
+
+	return berr
+}
@@ -0,0 +1,140 @@
+package render_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/svett/pho"
+	"github.com/svett/pho/render"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type Address struct {
+	City    string `json:"city" validate:"required"`
+	ZipCode string `json:"zip_code" validate:"len=5"`
+}
+
+type CreateUserInput struct {
+	Name    string  `json:"name" validate:"required,min=3"`
+	Age     int     `json:"age" validate:"gte=18"`
+	Address Address `json:"address"`
+}
+
+type RenameUserInput struct {
+	Name     string `json:"name"`
+	Previous string `json:"previous"`
+}
+
+func (in *RenameUserInput) Bind(r *pho.Request) error {
+	if in.Name == in.Previous {
+		return &render.BindError{
+			Message:     "The name is not changed",
+			FieldErrors: []pho.FieldError{{Field: "name", Message: "The name is the previous name"}},
+		}
+	}
+	return nil
+}
+
+var _ = Describe("Bind", func() {
+	var (
+		router *pho.Mux
+		server *httptest.Server
+		client *pho.Client
+		bound  chan interface{}
+	)
+
+	BeforeEach(func() {
+		bound = make(chan interface{}, 1)
+
+		router = pho.NewMux()
+		router.On("user:create", func(w pho.SocketWriter, r *pho.Request) {
+			in := &CreateUserInput{}
+			if err := render.Bind(w, r, in); err != nil {
+				return
+			}
+
+			bound <- in
+			w.Write(r.Type, http.StatusOK, r.Body)
+		})
+		router.On("user:rename", func(w pho.SocketWriter, r *pho.Request) {
+			in := &RenameUserInput{}
+			if err := render.Bind(w, r, in); err != nil {
+				return
+			}
+
+			bound <- in
+			w.Write(r.Type, http.StatusOK, r.Body)
+		})
+
+		server = httptest.NewServer(router)
+
+		var err error
+		client, err = pho.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		client.Close()
+		router.Close()
+		server.Close()
+	})
+
+	It("binds the valid request body", func() {
+		_, err := client.Call(context.Background(), "user:create", []byte(`{"name":"jack","age":21,"address":{"city":"Sofia","zip_code":"10000"}}`))
+		Expect(err).To(BeNil())
+
+		Eventually(bound).Should(Receive(Equal(&CreateUserInput{
+			Name:    "jack",
+			Age:     21,
+			Address: Address{City: "Sofia", ZipCode: "10000"},
+		})))
+	})
+
+	It("responds with 400 Bad Request to a malformed body", func() {
+		_, err := client.Call(context.Background(), "user:create", []byte(`{"name":42}`))
+
+		var rerr *pho.ResponseError
+		Expect(errors.As(err, &rerr)).To(BeTrue())
+		Expect(rerr.StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(rerr.Message).To(HavePrefix("The request body is malformed: "))
+		Expect(bound).NotTo(Receive())
+	})
+
+	It("responds with 422 Unprocessable Entity with the invalid fields", func() {
+		_, err := client.Call(context.Background(), "user:create", []byte(`{"name":"jo","age":17,"address":{"zip_code":"100"}}`))
+
+		var rerr *pho.ResponseError
+		Expect(errors.As(err, &rerr)).To(BeTrue())
+		Expect(rerr.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+		Expect(rerr.Message).To(Equal("The request body is invalid"))
+		Expect(rerr.Fields).To(ConsistOf(
+			pho.FieldError{Field: "name", Message: `The value does not satisfy "min=3"`},
+			pho.FieldError{Field: "age", Message: `The value does not satisfy "gte=18"`},
+			pho.FieldError{Field: "address.city", Message: `The value does not satisfy "required"`},
+			pho.FieldError{Field: "address.zip_code", Message: `The value does not satisfy "len=5"`},
+		))
+		Expect(bound).NotTo(Receive())
+	})
+
+	It("responds with 422 Unprocessable Entity to a Binder error", func() {
+		_, err := client.Call(context.Background(), "user:rename", []byte(`{"name":"jack","previous":"jack"}`))
+
+		var rerr *pho.ResponseError
+		Expect(errors.As(err, &rerr)).To(BeTrue())
+		Expect(rerr.StatusCode).To(Equal(http.StatusUnprocessableEntity))
+		Expect(rerr.Message).To(Equal("The name is not changed"))
+		Expect(rerr.Fields).To(Equal([]pho.FieldError{{Field: "name", Message: "The name is the previous name"}}))
+		Expect(bound).NotTo(Receive())
+	})
+
+	It("calls the Binder of the valid request body", func() {
+		_, err := client.Call(context.Background(), "user:rename", []byte(`{"name":"jill","previous":"jack"}`))
+		Expect(err).To(BeNil())
+		Eventually(bound).Should(Receive(Equal(&RenameUserInput{Name: "jill", Previous: "jack"})))
+	})
+})
@@ -8,12 +8,35 @@ import (
 // Header information provided by the client
 type Header map[string]string
 
+// Get returns the value of the key
+func (h Header) Get(key string) string {
+	return h[key]
+}
+
+// Set sets the value of the key
+func (h Header) Set(key, value string) {
+	h[key] = value
+}
+
+// Keys returns the keys of the header
+func (h Header) Keys() []string {
+	keys := make([]string, 0, len(h))
+	for key := range h {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
 // A Request represents an RPC request received by a server
 // or to be sent by a client.
 type Request struct {
 	// Request context
 	ctx context.Context
 
+	// ID correlates the request with its responses. The server echoes it
+	// on every response written while the request is handled.
+	ID string `json:"id,omitempty"`
+
 	// Type provides the name of the request
 	Type string `json:"Type,omitempty"`
 
@@ -1,9 +1,18 @@
 package pho
 
-import "encoding/json"
+import (
+	"context"
+	"encoding/json"
+)
+
+// responseHeaderCtxKey is the context key of the response header
+var responseHeaderCtxKey = &contextKey{"ResponseHeader"}
 
 // A Response represents an RPC response sent by a server
 type Response struct {
+	// ID of the request that this response answers
+	ID string `json:"id,omitempty"`
+
 	// Type provides the name of the request
 	Type string `json:"type,omitempty"`
 
@@ -16,3 +25,28 @@ type Response struct {
 	// Payload is the response's payload.
 	Payload json.RawMessage `json:"payload"`
 }
+
+// ResponseError is an error response sent by the server
+type ResponseError struct {
+	// StatusCode of the error response
+	StatusCode int
+	// Message describes the error
+	Message string
+	// Header of the error response
+	Header Header
+	// Fields are the invalid fields of the request body
+	Fields []FieldError
+}
+
+// Error returns the error message
+func (e *ResponseError) Error() string {
+	return e.Message
+}
+
+// ResponseHeader returns the header sent with every response to the request
+// of the context, whether or not the request has an ID. The responses
+// written to the other sockets do not carry it.
+func ResponseHeader(ctx context.Context) Header {
+	header, _ := ctx.Value(responseHeaderCtxKey).(Header)
+	return header
+}
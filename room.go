@@ -0,0 +1,114 @@
+package pho
+
+import "fmt"
+
+// rooms tracks the sockets that joined each room
+type rooms struct {
+	// members is the set of sockets in each room
+	members map[string]map[string]struct{}
+	// joined is the set of rooms of each socket
+	joined map[string]map[string]struct{}
+}
+
+func newRooms() *rooms {
+	return &rooms{
+		members: map[string]map[string]struct{}{},
+		joined:  map[string]map[string]struct{}{},
+	}
+}
+
+func (r *rooms) join(socketID, room string) {
+	if _, ok := r.members[room]; !ok {
+		r.members[room] = map[string]struct{}{}
+	}
+
+	if _, ok := r.joined[socketID]; !ok {
+		r.joined[socketID] = map[string]struct{}{}
+	}
+
+	r.members[room][socketID] = struct{}{}
+	r.joined[socketID][room] = struct{}{}
+}
+
+func (r *rooms) leave(socketID, room string) {
+	delete(r.members[room], socketID)
+	if len(r.members[room]) == 0 {
+		delete(r.members, room)
+	}
+
+	delete(r.joined[socketID], room)
+	if len(r.joined[socketID]) == 0 {
+		delete(r.joined, socketID)
+	}
+}
+
+func (r *rooms) leaveAll(socketID string) {
+	for room := range r.joined[socketID] {
+		r.leave(socketID, room)
+	}
+}
+
+// Join adds the socket to the room. The room is created if it does not exist.
+func (m *Mux) Join(socketID, room string) error {
+	m.rw.Lock()
+	defer m.rw.Unlock()
+
+	if _, ok := m.sockets[socketID]; !ok {
+		return fmt.Errorf("The socket %q does not exist", socketID)
+	}
+
+	m.rooms.join(socketID, room)
+	return nil
+}
+
+// Leave removes the socket from the room. The room is deleted when its last
+// member leaves.
+func (m *Mux) Leave(socketID, room string) {
+	m.rw.Lock()
+	defer m.rw.Unlock()
+	m.rooms.leave(socketID, room)
+}
+
+// Members returns the sockets that joined the room
+func (m *Mux) Members(room string) WebSockets {
+	m.rw.RLock()
+	defer m.rw.RUnlock()
+
+	members := WebSockets{}
+	for socketID := range m.rooms.members[room] {
+		members[socketID] = m.sockets[socketID]
+	}
+
+	return members
+}
+
+// Rooms returns the rooms joined by the socket
+func (m *Mux) Rooms(socketID string) []string {
+	m.rw.RLock()
+	defer m.rw.RUnlock()
+
+	rooms := []string{}
+	for room := range m.rooms.joined[socketID] {
+		rooms = append(rooms, room)
+	}
+
+	return rooms
+}
+
+// Broadcast writes a response to all members of the room. Write errors are
+// reported to the OnError callback.
+func (m *Mux) Broadcast(room, verb string, status int, body []byte) {
+	m.BroadcastExcept(room, "", verb, status, body)
+}
+
+// BroadcastExcept writes a response to all members of the room except the
+// provided socket. Write errors are reported to the OnError callback.
+func (m *Mux) BroadcastExcept(room, socketID, verb string, status int, body []byte) {
+	for id, socket := range m.Members(room) {
+		if id == socketID {
+			continue
+		}
+
+		m.handleError(socket.Write(verb, status, body))
+	}
+}
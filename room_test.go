@@ -0,0 +1,160 @@
+package pho_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/svett/pho"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Rooms", func() {
+	var (
+		router *pho.Mux
+		server *httptest.Server
+		url    string
+	)
+
+	BeforeEach(func() {
+		router = pho.NewMux()
+		server = httptest.NewServer(router)
+		url = fmt.Sprintf("ws://%s", server.Listener.Addr().String())
+
+		router.On("join", func(w pho.SocketWriter, r *pho.Request) {
+			defer GinkgoRecover()
+			Expect(router.Join(w.SocketID(), string(r.Body))).To(Succeed())
+			Expect(w.Write("joined", http.StatusOK, r.Body)).To(Succeed())
+		})
+	})
+
+	AfterEach(func() {
+		router.Close()
+		server.Close()
+	})
+
+	join := func(room string) *pho.Client {
+		client, err := pho.Dial(url, nil)
+		Expect(err).To(BeNil())
+
+		joined := make(chan struct{})
+		client.On("joined", func(resp *pho.Response) {
+			close(joined)
+		})
+
+		Expect(client.Write("join", []byte(room))).To(Succeed())
+		Eventually(joined).Should(BeClosed())
+		return client
+	}
+
+	It("lists the members of the room", func() {
+		clientA := join(`"lobby"`)
+		defer clientA.Close()
+
+		clientB := join(`"lobby"`)
+		defer clientB.Close()
+
+		clientC := join(`"kitchen"`)
+		defer clientC.Close()
+
+		Expect(router.Members(`"lobby"`)).To(HaveLen(2))
+		Expect(router.Members(`"kitchen"`)).To(HaveLen(1))
+		Expect(router.Members(`"garden"`)).To(BeEmpty())
+
+		for id := range router.Members(`"kitchen"`) {
+			Expect(router.Rooms(id)).To(ConsistOf(`"kitchen"`))
+		}
+	})
+
+	It("broadcasts to all members of the room", func() {
+		mu := &sync.Mutex{}
+		received := []string{}
+
+		receive := func(name string) pho.OnResponseFunc {
+			return func(resp *pho.Response) {
+				mu.Lock()
+				defer mu.Unlock()
+				received = append(received, name+" "+string(resp.Payload))
+			}
+		}
+
+		clientA := join(`"lobby"`)
+		defer clientA.Close()
+		clientA.On("message", receive("a"))
+
+		clientB := join(`"lobby"`)
+		defer clientB.Close()
+		clientB.On("message", receive("b"))
+
+		clientC := join(`"kitchen"`)
+		defer clientC.Close()
+		clientC.On("message", receive("c"))
+
+		router.Broadcast(`"lobby"`, "message", http.StatusOK, []byte(`"hi"`))
+
+		Eventually(func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			return received
+		}).Should(ConsistOf(`a "hi"`, `b "hi"`))
+	})
+
+	It("broadcasts to all members of the room except the sender", func() {
+		router.On("say", func(w pho.SocketWriter, r *pho.Request) {
+			router.BroadcastExcept(`"lobby"`, w.SocketID(), "message", http.StatusOK, r.Body)
+		})
+
+		clientA := join(`"lobby"`)
+		defer clientA.Close()
+
+		cntA := 0
+		clientA.On("message", func(resp *pho.Response) {
+			cntA++
+		})
+
+		clientB := join(`"lobby"`)
+		defer clientB.Close()
+
+		cntB := 0
+		clientB.On("message", func(resp *pho.Response) {
+			cntB++
+		})
+
+		Expect(clientA.Write("say", []byte(`"hi"`))).To(Succeed())
+		Eventually(func() int { return cntB }).Should(Equal(1))
+		Consistently(func() int { return cntA }).Should(Equal(0))
+	})
+
+	Context("when the socket does not exist", func() {
+		It("returns an error", func() {
+			Expect(router.Join("unknown", "lobby")).To(MatchError(`The socket "unknown" does not exist`))
+		})
+	})
+
+	Context("when the socket leaves the room", func() {
+		It("is no longer a member", func() {
+			client := join(`"lobby"`)
+			defer client.Close()
+
+			for id := range router.Members(`"lobby"`) {
+				router.Leave(id, `"lobby"`)
+			}
+
+			Expect(router.Members(`"lobby"`)).To(BeEmpty())
+		})
+	})
+
+	Context("when the socket is disconnected", func() {
+		It("leaves all rooms", func() {
+			client := join(`"lobby"`)
+			defer client.Close()
+
+			router.Close()
+			Expect(client.Write("hello", []byte(`"world"`))).To(Succeed())
+			Eventually(func() pho.WebSockets { return router.Members(`"lobby"`) }).Should(BeEmpty())
+		})
+	})
+})
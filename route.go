@@ -0,0 +1,171 @@
+package pho
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+var routeCtxKey = &contextKey{"RouteContext"}
+
+// contextKey is a value for use with context.WithValue. It's used as
+// a pointer so it fits in an interface{} without allocation.
+type contextKey struct {
+	name string
+}
+
+func (k *contextKey) String() string {
+	return "pho context value " + k.name
+}
+
+// RouteContext is the routing state of a request
+type RouteContext struct {
+	// Verb is the verb of the request before it was routed
+	Verb string
+	// Params are the parameters captured by the pattern routes
+	Params map[string]string
+	// RoutePatterns are the routes matched by the nested routers (ex.
+	// "document:*" followed by "{id}:open")
+	RoutePatterns []string
+
+	// mounted is true when the request is routed to a mounted namespace
+	mounted bool
+	// notFound is the inherited handler of the unknown verbs
+	notFound HandlerFunc
+	// methodNotAllowed is the inherited handler of the unknown verbs of a
+	// mounted namespace
+	methodNotAllowed HandlerFunc
+}
+
+// GetRouteContext returns the routing state of the request context
+func GetRouteContext(ctx context.Context) *RouteContext {
+	rctx, _ := ctx.Value(routeCtxKey).(*RouteContext)
+	return rctx
+}
+
+// RoutePattern returns the route that matched the verb (ex.
+// "document:{id}:open"). It is empty when no route matched it.
+func (rctx *RouteContext) RoutePattern() string {
+	patterns := make([]string, len(rctx.RoutePatterns))
+
+	for index, route := range rctx.RoutePatterns {
+		if index < len(rctx.RoutePatterns)-1 {
+			route = strings.TrimSuffix(route, ":*")
+		}
+		patterns[index] = route
+	}
+
+	return strings.Join(patterns, ":")
+}
+
+// VerbParam returns the verb parameter captured by a pattern route. The
+// parameter of the wildcard segment is "*".
+func VerbParam(r *Request, key string) string {
+	if rctx := GetRouteContext(r.Context()); rctx != nil {
+		return rctx.Params[key]
+	}
+	return ""
+}
+
+type segmentKind int
+
+// The kinds are ordered by their precedence
+const (
+	segmentEnd segmentKind = iota
+	segmentWildcard
+	segmentParam
+	segmentStatic
+)
+
+type segment struct {
+	kind  segmentKind
+	value string
+}
+
+// namespace is the shape of a handler mounted at the first segment
+var namespace = []segment{{kind: segmentStatic}, {kind: segmentWildcard}}
+
+// pattern is a route that has parameters or a wildcard
+type pattern struct {
+	verb     string
+	segments []segment
+	handler  Handler
+}
+
+// isPattern returns true if the verb has parameters or a wildcard
+func isPattern(verb string) bool {
+	return strings.ContainsAny(verb, "{*")
+}
+
+func newPattern(verb string, handler Handler) (*pattern, error) {
+	parts := strings.Split(verb, ":")
+	segments := make([]segment, len(parts))
+
+	for index, part := range parts {
+		switch {
+		case part == "*":
+			if index != len(parts)-1 {
+				return nil, fmt.Errorf("The wildcard must be the last segment of %q", verb)
+			}
+			segments[index] = segment{kind: segmentWildcard, value: "*"}
+		case strings.HasPrefix(part, "{") && strings.HasSuffix(part, "}") && len(part) > 2:
+			segments[index] = segment{kind: segmentParam, value: part[1 : len(part)-1]}
+		case strings.ContainsAny(part, "{}*") || part == "":
+			return nil, fmt.Errorf("The segment %q of %q is invalid", part, verb)
+		default:
+			segments[index] = segment{kind: segmentStatic, value: strings.ToLower(part)}
+		}
+	}
+
+	return &pattern{verb: verb, segments: segments, handler: handler}, nil
+}
+
+// match matches the pattern against the verb segments and returns the
+// captured parameters
+func (p *pattern) match(parts []string) (map[string]string, bool) {
+	params := map[string]string{}
+
+	for index, segment := range p.segments {
+		if index >= len(parts) || parts[index] == "" {
+			return nil, false
+		}
+
+		switch segment.kind {
+		case segmentWildcard:
+			params[segment.value] = strings.Join(parts[index:], ":")
+			return params, true
+		case segmentParam:
+			params[segment.value] = parts[index]
+		case segmentStatic:
+			if !strings.EqualFold(segment.value, parts[index]) {
+				return nil, false
+			}
+		}
+	}
+
+	return params, len(parts) == len(p.segments)
+}
+
+// precedence compares two route shapes segment by segment. A static segment
+// precedes a parameter that precedes a wildcard. It returns a positive value
+// if a precedes b, a negative value if b precedes a and zero otherwise.
+func precedence(a, b []segment) int {
+	for index := 0; ; index++ {
+		kindA, kindB := segmentAt(a, index), segmentAt(b, index)
+
+		if kindA != kindB {
+			return int(kindA) - int(kindB)
+		}
+
+		if kindA == segmentEnd || kindA == segmentWildcard {
+			return 0
+		}
+	}
+}
+
+func segmentAt(segments []segment, index int) segmentKind {
+	if index < len(segments) {
+		return segments[index].kind
+	}
+	return segmentEnd
+}
@@ -0,0 +1,163 @@
+package pho_test
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"sync"
+
+	"github.com/svett/pho"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Route", func() {
+	var (
+		router *pho.Mux
+		server *httptest.Server
+		client *pho.Client
+		mu     *sync.Mutex
+		routes []string
+	)
+
+	handle := func(name string) pho.HandlerFunc {
+		return func(w pho.SocketWriter, r *pho.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			rctx := pho.GetRouteContext(r.Context())
+			routes = append(routes, fmt.Sprintf("%s %s %v", name, r.Type, rctx.Params))
+		}
+	}
+
+	received := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return routes
+	}
+
+	BeforeEach(func() {
+		mu = &sync.Mutex{}
+		routes = []string{}
+
+		router = pho.NewMux()
+		server = httptest.NewServer(router)
+
+		var err error
+		client, err = pho.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+		Expect(err).To(BeNil())
+	})
+
+	AfterEach(func() {
+		client.Close()
+		router.Close()
+		server.Close()
+	})
+
+	It("captures the parameters", func() {
+		router.On("user:{id}:update", func(w pho.SocketWriter, r *pho.Request) {
+			defer GinkgoRecover()
+			Expect(pho.VerbParam(r, "id")).To(Equal("Jack42"))
+			handle("update")(w, r)
+		})
+
+		Expect(client.Write("user:Jack42:update", []byte(`""`))).To(Succeed())
+		Eventually(received).Should(ConsistOf("update user:Jack42:update map[id:Jack42]"))
+	})
+
+	It("matches the wildcard routes", func() {
+		router.On("document:*", handle("document"))
+
+		Expect(client.Write("document:blocks:close", []byte(`""`))).To(Succeed())
+		Eventually(received).Should(ConsistOf("document document:blocks:close map[*:blocks:close]"))
+	})
+
+	It("matches the catch-all route", func() {
+		router.On("*", handle("all"))
+
+		Expect(client.Write("anything", []byte(`""`))).To(Succeed())
+		Eventually(received).Should(ConsistOf("all anything map[*:anything]"))
+	})
+
+	It("prefers the exact verb", func() {
+		router.On("user:{id}", handle("param"))
+		router.On("user:list", handle("exact"))
+
+		Expect(client.Write("user:list", []byte(`""`))).To(Succeed())
+		Eventually(received).Should(ConsistOf("exact user:list map[]"))
+	})
+
+	It("prefers the static segments to the parameters and the wildcards", func() {
+		router.On("*", handle("all"))
+		router.On("user:*", handle("wildcard"))
+		router.On("{kind}:{id}:update", handle("kind"))
+		router.On("user:{id}:update", handle("user"))
+
+		Expect(client.Write("user:1:update", []byte(`""`))).To(Succeed())
+		Eventually(received).Should(ConsistOf("user user:1:update map[id:1]"))
+
+		Expect(client.Write("group:1:update", []byte(`""`))).To(Succeed())
+		Eventually(received).Should(ContainElement("kind group:1:update map[id:1 kind:group]"))
+
+		Expect(client.Write("user:1:delete", []byte(`""`))).To(Succeed())
+		Eventually(received).Should(ContainElement("wildcard user:1:delete map[*:1:delete]"))
+
+		Expect(client.Write("group:1:delete", []byte(`""`))).To(Succeed())
+		Eventually(received).Should(ContainElement("all group:1:delete map[*:group:1:delete]"))
+	})
+
+	It("prefers the mounted namespace to the pattern routes of the same shape", func() {
+		router.Route("user", func(r pho.Router) {
+			r.On("{id}", handle("namespace"))
+		})
+		router.On("user:*", handle("wildcard"))
+		router.On("user:{id}:update", handle("pattern"))
+
+		Expect(client.Write("user:1", []byte(`""`))).To(Succeed())
+		Eventually(received).Should(ConsistOf("namespace 1 map[id:1]"))
+
+		Expect(client.Write("user:1:update", []byte(`""`))).To(Succeed())
+		Eventually(received).Should(ContainElement("pattern user:1:update map[id:1]"))
+	})
+
+	It("provides the route pattern", func() {
+		pattern := func(w pho.SocketWriter, r *pho.Request) {
+			mu.Lock()
+			defer mu.Unlock()
+
+			routes = append(routes, pho.GetRouteContext(r.Context()).RoutePattern())
+		}
+
+		router.Route("document", func(r pho.Router) {
+			r.On("{id}:open", pattern)
+			r.On("list", pattern)
+		})
+		router.On("user:*", pattern)
+
+		Expect(client.Write("document:1:open", []byte(`""`))).To(Succeed())
+		Eventually(received).Should(ContainElement("document:{id}:open"))
+
+		Expect(client.Write("Document:List", []byte(`""`))).To(Succeed())
+		Eventually(received).Should(ContainElement("document:list"))
+
+		Expect(client.Write("user:1:delete", []byte(`""`))).To(Succeed())
+		Eventually(received).Should(ContainElement("user:*"))
+	})
+
+	Context("when the pattern is invalid", func() {
+		It("reports an error", func() {
+			errs := []error{}
+			router.OnError(func(err error) {
+				errs = append(errs, err)
+			})
+
+			router.On("user:*:update", handle("invalid"))
+			router.On("user:{}", handle("invalid"))
+
+			Expect(errs).To(ConsistOf(
+				MatchError(`The wildcard must be the last segment of "user:*:update"`),
+				MatchError(`The segment "{}" of "user:{}" is invalid`),
+			))
+		})
+	})
+})
@@ -1,16 +1,57 @@
 package pho
 
 import (
+	"context"
 	"crypto/tls"
-	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 )
 
 type SocketError struct {
-	Error string `json:"error"`
+	Error  string       `json:"error"`
+	Fields []FieldError `json:"fields,omitempty"`
+}
+
+// FieldError describes an invalid field of the request body
+type FieldError struct {
+	// Field is the path of the field (ex. "address.city")
+	Field string `json:"field"`
+	// Message describes why the field is invalid
+	Message string `json:"message"`
+}
+
+// FieldsError is an error that lists the invalid fields in its error response
+type FieldsError interface {
+	error
+	// Fields are the invalid fields
+	Fields() []FieldError
+}
+
+// HeaderError is an error that provides the header of its error response
+type HeaderError interface {
+	error
+	// Header of the error response
+	Header() Header
+}
+
+// MessageSizeError is reported when a message exceeds the size limit
+type MessageSizeError struct {
+	// SocketID is the ID of the socket that sent the message
+	SocketID string
+	// Size of the message when it is known
+	Size int64
+	// Limit is the maximum size of the message
+	Limit int64
+}
+
+// Error returns the error message
+func (e *MessageSizeError) Error() string {
+	return fmt.Sprintf("The message of socket %q exceeds the limit of %d bytes", e.SocketID, e.Limit)
 }
 
 // SocketOptions provides the socket options
@@ -24,6 +65,26 @@ type SocketOptions struct {
 	OnDisconnect OnDisconnectFunc
 	OnError      OnErrorFunc
 	StopChan     chan struct{}
+	QueueSize    int
+	QueuePolicy  QueuePolicy
+	PingInterval time.Duration
+	PongTimeout  time.Duration
+	ReadDeadline time.Duration
+	CloseTimeout time.Duration
+	Codec        Codec
+	// Concurrency is the number of requests served at a time (zero serves
+	// the requests one by one)
+	Concurrency int
+	// Workers limits the concurrent requests across sockets
+	Workers *WorkerPool
+	// Ordered reports the verbs served in the order of arrival when the
+	// socket serves requests concurrently
+	Ordered OrderedFunc
+	// Principal is the authenticated identity of the client
+	Principal Principal
+	// MaxMessageSize is the maximum size in bytes of a message read from the
+	// connection (zero means no limit)
+	MaxMessageSize int64
 }
 
 // Socket represents a single client connection
@@ -40,6 +101,30 @@ type Socket struct {
 	serveRPCFn     HandlerFunc
 	onDisconnectFn OnDisconnectFunc
 	onErrorFn      OnErrorFunc
+	queue          chan []byte
+	queuePolicy    QueuePolicy
+	closeOnce      sync.Once
+	closeChan      chan struct{}
+	heartbeat      *heartbeat
+	pingInterval   time.Duration
+	reasonOnce     sync.Once
+	reason         error
+	codec          Codec
+	shutdownOnce   sync.Once
+	shutdownChan   chan struct{}
+	closeFrame     []byte
+	closeTimeout   time.Duration
+	slots          chan struct{}
+	serial         chan func()
+	workers        *WorkerPool
+	ordered        OrderedFunc
+	last           chan struct{}
+	handlers       sync.WaitGroup
+	ctx            context.Context
+	cancel         context.CancelFunc
+	pendingMu      sync.Mutex
+	pending        map[string]*context.CancelFunc
+	maxMessageSize int64
 }
 
 // NewSocket creates a new socket
@@ -53,6 +138,28 @@ func NewSocket(options *SocketOptions) (*Socket, error) {
 		return nil, err
 	}
 
+	queueSize := options.QueueSize
+	if queueSize <= 0 {
+		queueSize = DefaultWriteQueueSize
+	}
+
+	codec := options.Codec
+	if codec == nil {
+		codec = JSONCodec
+	}
+
+	pingInterval := options.PingInterval
+	if pingInterval == 0 {
+		pingInterval = PingInterval
+	}
+
+	closeTimeout := options.CloseTimeout
+	if closeTimeout <= 0 {
+		closeTimeout = CloseTimeout
+	}
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), principalCtxKey, options.Principal))
+
 	socket := &Socket{
 		id:             socketID,
 		tls:            options.TLS,
@@ -64,7 +171,31 @@ func NewSocket(options *SocketOptions) (*Socket, error) {
 		serveRPCFn:     options.ServeRPC,
 		onDisconnectFn: options.OnDisconnect,
 		onErrorFn:      options.OnError,
-		metadata:       Metadata{},
+		metadata:       Metadata{MetadataCodecKey: codec, MetadataPrincipalKey: options.Principal},
+		queue:          make(chan []byte, queueSize),
+		queuePolicy:    options.QueuePolicy,
+		closeChan:      make(chan struct{}),
+		shutdownChan:   make(chan struct{}),
+		heartbeat:      newHeartbeat(options.Conn, options.ReadDeadline, options.PongTimeout),
+		pingInterval:   pingInterval,
+		closeTimeout:   closeTimeout,
+		codec:          codec,
+		workers:        options.Workers,
+		ordered:        options.Ordered,
+		ctx:            ctx,
+		cancel:         cancel,
+		pending:        map[string]*context.CancelFunc{},
+		maxMessageSize: options.MaxMessageSize,
+	}
+
+	if options.MaxMessageSize > 0 {
+		options.Conn.SetReadLimit(options.MaxMessageSize)
+	}
+
+	if options.Concurrency > 0 {
+		socket.slots = make(chan struct{}, options.Concurrency)
+	} else {
+		socket.serial = make(chan func(), 1)
 	}
 
 	return socket, nil
@@ -80,31 +211,20 @@ func (c *Socket) Metadata() Metadata {
 	return c.metadata
 }
 
+// Context returns the socket context. It is cancelled when the socket is
+// disconnected or the mux is closed.
+func (c *Socket) Context() context.Context {
+	return c.ctx
+}
+
 // Write a reponse
 func (c *Socket) Write(responseType string, status int, data []byte) error {
-	response := &Response{
-		Type:       responseType,
-		StatusCode: status,
-		Payload:    data,
-	}
-
-	return c.write(response)
+	return c.reply("", nil, responseType, status, data)
 }
 
 // WriteError writes an errors with specified code
 func (c *Socket) WriteError(err error, code int) error {
-	body, _ := json.Marshal(&SocketError{
-		Error: err.Error(),
-	})
-
-	response := &Response{
-		Type:       "error",
-		StatusCode: code,
-		Payload:    body,
-	}
-
-	c.onErrorFn(err)
-	return c.write(response)
+	return c.replyError("", nil, err, code)
 }
 
 // The client user agent
@@ -127,43 +247,277 @@ func (c *Socket) RemoteAddr() string {
 	return c.conn.RemoteAddr().String()
 }
 
+func (c *Socket) reply(id string, header Header, responseType string, status int, data []byte) error {
+	response := &Response{
+		ID:         id,
+		Type:       responseType,
+		StatusCode: status,
+		Header:     header,
+		Payload:    data,
+	}
+
+	return c.write(response)
+}
+
+func (c *Socket) replyError(id string, header Header, err error, code int) error {
+	socketErr := &SocketError{
+		Error: err.Error(),
+	}
+
+	var ferr FieldsError
+	if errors.As(err, &ferr) {
+		socketErr.Fields = ferr.Fields()
+	}
+
+	body, _ := c.codec.Marshal(socketErr)
+
+	response := &Response{
+		ID:         id,
+		Type:       ErrorType,
+		StatusCode: code,
+		Header:     header,
+		Payload:    body,
+	}
+
+	var herr HeaderError
+	if errors.As(err, &herr) {
+		response.Header = Header{}
+
+		for key, value := range header {
+			response.Header[key] = value
+		}
+
+		for key, value := range herr.Header() {
+			response.Header[key] = value
+		}
+	}
+
+	c.onErrorFn(err)
+	return c.write(response)
+}
+
+// write encodes the response and puts it in the write queue
 func (c *Socket) write(response *Response) error {
-	writer, err := c.conn.NextWriter(websocket.BinaryMessage)
+	data, err := c.codec.Marshal(response)
 	if err != nil {
 		return err
 	}
 
-	enc := json.NewEncoder(writer)
-	enc.SetEscapeHTML(true)
+	return c.enqueue(data)
+}
+
+// enqueue puts the message in the write queue applying the queue policy
+// when the queue is full
+func (c *Socket) enqueue(data []byte) error {
+	select {
+	case <-c.shutdownChan:
+		return websocket.ErrCloseSent
+	default:
+	}
+
+	select {
+	case <-c.closeChan:
+		return ErrSocketClosed
+	case c.queue <- data:
+		return nil
+	default:
+	}
+
+	switch c.queuePolicy {
+	case QueueDropOldest:
+		for {
+			select {
+			case c.queue <- data:
+				c.onErrorFn(ErrQueueFull)
+				return nil
+			default:
+			}
+
+			select {
+			case <-c.queue:
+			default:
+			}
+		}
+	case QueueDropNewest:
+		return ErrQueueFull
+	case QueueDisconnect:
+		c.fail(ErrQueueFull)
+		c.close()
+		return ErrQueueFull
+	default:
+		select {
+		case <-c.closeChan:
+			return ErrSocketClosed
+		case c.queue <- data:
+			return nil
+		}
+	}
+}
+
+// flush writes the queued messages to the connection and pings the peer
+func (c *Socket) flush() {
+	var pingChan <-chan time.Time
 
-	if err = enc.Encode(response); err != nil {
-		if closeErr := writer.Close(); closeErr != nil {
-			err = fmt.Errorf("%v: %v", err, closeErr)
+	if c.pingInterval > 0 {
+		ticker := time.NewTicker(c.pingInterval)
+		defer ticker.Stop()
+		pingChan = ticker.C
+	}
+
+	for {
+		select {
+		case <-c.closeChan:
+			return
+		case <-pingChan:
+			if err := c.heartbeat.ping(); err != nil {
+				c.fail(err)
+				c.close()
+				return
+			}
+		case <-c.shutdownChan:
+			c.drain()
+			c.onErrorFn(c.conn.WriteControl(websocket.CloseMessage, c.closeFrame, time.Now().Add(WriteDeadline)))
+			// the peer that does not answer the close frame is dropped
+			time.AfterFunc(c.closeTimeout, c.close)
+			return
+		case data := <-c.queue:
+			if err := c.writeMessage(data); err != nil {
+				c.onErrorFn(err)
+				c.fail(err)
+				c.close()
+				return
+			}
 		}
+	}
+}
+
+// drain writes the messages left in the queue
+func (c *Socket) drain() {
+	for {
+		select {
+		case data := <-c.queue:
+			if err := c.writeMessage(data); err != nil {
+				c.onErrorFn(err)
+				return
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (c *Socket) writeMessage(data []byte) error {
+	if err := c.conn.SetWriteDeadline(time.Now().Add(WriteDeadline)); err != nil {
 		return err
 	}
-	return writer.Close()
+
+	return c.conn.WriteMessage(websocket.BinaryMessage, data)
+}
+
+// Disconnect sends a close frame with the provided code to the client once
+// the queued messages are written. The requests that arrive afterwards are
+// not served and the connection is closed when the client does not answer
+// within the close timeout. The reason is reported by DisconnectReason.
+func (c *Socket) Disconnect(code int, reason error) {
+	c.fail(reason)
+	c.shutdown(code, reason.Error())
+}
+
+// Disconnect disconnects the socket that sent the request of the context
+// like Socket.Disconnect does. Unlike a type assertion on the SocketWriter,
+// it works with the writers wrapped by the middlewares. It reports whether
+// the context belongs to a request of a socket.
+func Disconnect(ctx context.Context, code int, reason error) bool {
+	socket, ok := ctx.Value(socketCtxKey).(*Socket)
+	if !ok {
+		return false
+	}
+
+	socket.Disconnect(code, reason)
+	return true
+}
+
+// shutdown sends a close frame to the client once the queued messages are
+// written. The socket does not accept new messages afterwards.
+func (c *Socket) shutdown(code int, reason string) {
+	c.fail(ErrServerClosed)
+	c.shutdownOnce.Do(func() {
+		c.closeFrame = websocket.FormatCloseMessage(code, reason)
+		close(c.shutdownChan)
+	})
+}
+
+// fail records the reason for disconnecting the socket. Only the first
+// reason is kept.
+func (c *Socket) fail(err error) {
+	c.reasonOnce.Do(func() {
+		c.reason = err
+	})
+}
+
+// disconnect reports the disconnect reason and calls the disconnect callback
+func (c *Socket) disconnect(err error) {
+	c.fail(err)
+	c.metadata[MetadataDisconnectKey] = c.reason
+	c.onDisconnectFn(c)
+}
+
+// close stops the writer and closes the connection
+func (c *Socket) close() {
+	c.closeOnce.Do(func() {
+		c.cancel()
+		close(c.closeChan)
+		c.onErrorFn(c.conn.Close())
+	})
+}
+
+// start starts the writer, so that the socket can be written before it
+// reads the requests
+func (c *Socket) start() {
+	go c.flush()
 }
 
 // run listens for server responses
 func (c *Socket) run() {
+	if c.serial != nil {
+		go c.serveSerial()
+	}
+
+	// the context must be cancelled even if the read loop is blocked
+	// dispatching a request
+	go func() {
+		select {
+		case <-c.stopChan:
+		case <-c.closeChan:
+		}
+		c.cancel()
+	}()
+
 	for {
 		select {
 		case <-c.stopChan:
-			c.onDisconnectFn(c)
+			c.cancel()
+			c.stopSerial()
+			c.handlers.Wait()
+			c.disconnect(ErrServerClosed)
 			c.onErrorFn(c.conn.WriteControl(websocket.CloseMessage, []byte{}, time.Now().Add(30*time.Second)))
-			c.onErrorFn(c.conn.Close())
+			c.close()
 			return
 		default:
-			if err := c.conn.SetReadDeadline(time.Now().Add(ReadDeadline)); err != nil {
-				c.onErrorFn(err)
-				continue
+			// the deadline cannot be set once the connection is closed
+			if err := c.heartbeat.extend(); err != nil {
+				c.stop(err)
+				return
 			}
 
 			msgType, reader, err := c.conn.NextReader()
 			if err != nil {
-				c.onDisconnectFn(c)
-				c.onErrorFn(c.conn.Close())
+				if err == websocket.ErrReadLimit {
+					err = &MessageSizeError{SocketID: c.id, Limit: c.maxMessageSize}
+					c.onErrorFn(err)
+				}
+
+				c.stop(c.heartbeat.reason(err))
 				return
 			}
 
@@ -171,13 +525,67 @@ func (c *Socket) run() {
 				continue
 			}
 
+			data, err := ioutil.ReadAll(reader)
+			if err != nil {
+				// the next read reports the exceeded limit
+				if err != websocket.ErrReadLimit {
+					c.onErrorFn(err)
+				}
+				continue
+			}
+
 			request := &Request{}
-			if err := json.NewDecoder(reader).Decode(request); err != nil {
+			if err := c.codec.Unmarshal(data, request); err != nil {
 				c.onErrorFn(err)
 				continue
 			}
 
-			c.serveRPCFn(c, request)
+			if request.Type == CancelType {
+				c.cancelRequest(request.ID)
+				continue
+			}
+
+			// the requests that arrive after the close frame are dropped
+			select {
+			case <-c.shutdownChan:
+				continue
+			default:
+			}
+
+			c.dispatch(request)
 		}
 	}
 }
+
+// stop waits for the dispatched requests, disconnects the socket with the
+// provided reason and closes the connection
+func (c *Socket) stop(reason error) {
+	c.cancel()
+	c.stopSerial()
+	c.handlers.Wait()
+	c.disconnect(reason)
+	c.close()
+}
+
+// replyWriter stamps the ID of the request being served on every response
+type replyWriter struct {
+	*Socket
+	id       string
+	header   Header
+	metadata Metadata
+}
+
+// Metadata for this request
+func (w *replyWriter) Metadata() Metadata {
+	return w.metadata
+}
+
+// Write a reponse to the request
+func (w *replyWriter) Write(responseType string, status int, data []byte) error {
+	return w.reply(w.id, w.header, responseType, status, data)
+}
+
+// WriteError writes an error response to the request
+func (w *replyWriter) WriteError(err error, code int) error {
+	return w.replyError(w.id, w.header, err, code)
+}
@@ -1,9 +1,15 @@
 package pho_test
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
 
 	"github.com/svett/pho"
 
@@ -72,4 +78,204 @@ var _ = Describe("Sockets", func() {
 		})
 	})
 
+	Context("when writes an error with a header", func() {
+		It("sends the header of the error", func() {
+			router.On("message", func(w pho.SocketWriter, req *pho.Request) {
+				w.WriteError(&headerError{}, http.StatusTooManyRequests)
+			})
+
+			client, err := pho.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+			Expect(err).To(BeNil())
+			defer client.Close()
+
+			_, err = client.Call(context.Background(), "message", []byte(`""`))
+			Expect(err).To(Equal(&pho.ResponseError{
+				StatusCode: http.StatusTooManyRequests,
+				Message:    "slow down",
+				Header:     pho.Header{"Retry-After": "1"},
+			}))
+		})
+	})
+
+	Context("when writes an error with invalid fields", func() {
+		It("sends the invalid fields", func() {
+			router.On("message", func(w pho.SocketWriter, req *pho.Request) {
+				w.WriteError(&fieldsError{}, http.StatusUnprocessableEntity)
+			})
+
+			client, err := pho.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+			Expect(err).To(BeNil())
+			defer client.Close()
+
+			_, err = client.Call(context.Background(), "message", []byte(`""`))
+			Expect(err).To(Equal(&pho.ResponseError{
+				StatusCode: http.StatusUnprocessableEntity,
+				Message:    "invalid body",
+				Fields:     []pho.FieldError{{Field: "name", Message: "required"}},
+			}))
+		})
+	})
+
+	Context("when the socket is disconnected", func() {
+		It("sends the close code and reports the reason", func() {
+			reason := fmt.Errorf("go away")
+
+			router.On("message", func(w pho.SocketWriter, req *pho.Request) {
+				w.(interface {
+					Disconnect(code int, reason error)
+				}).Disconnect(websocket.ClosePolicyViolation, reason)
+			})
+
+			reasons := make(chan error, 1)
+			router.OnDisconnect(func(w pho.SocketWriter) {
+				reasons <- pho.DisconnectReason(w)
+			})
+
+			conn, _, err := websocket.DefaultDialer.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+			Expect(err).To(BeNil())
+			defer conn.Close()
+
+			Expect(conn.WriteJSON(&pho.Request{Type: "message", Body: []byte(`""`)})).To(Succeed())
+
+			_, _, err = conn.ReadMessage()
+			Expect(websocket.IsCloseError(err, websocket.ClosePolicyViolation)).To(BeTrue())
+			Eventually(reasons).Should(Receive(Equal(reason)))
+		})
+
+		Context("when the client does not answer the close frame", func() {
+			BeforeEach(func() {
+				router.Close()
+				server.Close()
+
+				router = pho.NewMuxWithOptions(&pho.MuxOptions{
+					CloseTimeout: 100 * time.Millisecond,
+				})
+				server = httptest.NewServer(router)
+			})
+
+			It("drops the requests and closes the connection", func() {
+				reason := fmt.Errorf("go away")
+				disconnected := make(chan struct{})
+
+				router.On("message", func(w pho.SocketWriter, req *pho.Request) {
+					w.(interface {
+						Disconnect(code int, reason error)
+					}).Disconnect(websocket.ClosePolicyViolation, reason)
+					close(disconnected)
+				})
+
+				var served int32
+				router.On("spam", func(w pho.SocketWriter, req *pho.Request) {
+					atomic.AddInt32(&served, 1)
+				})
+
+				reasons := make(chan error, 1)
+				router.OnDisconnect(func(w pho.SocketWriter) {
+					reasons <- pho.DisconnectReason(w)
+				})
+
+				conn, _, err := websocket.DefaultDialer.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+				Expect(err).To(BeNil())
+				defer conn.Close()
+
+				Expect(conn.WriteJSON(&pho.Request{Type: "message", Body: []byte(`""`)})).To(Succeed())
+				Eventually(disconnected).Should(BeClosed())
+
+				for i := 0; i < 50; i++ {
+					Expect(conn.WriteJSON(&pho.Request{Type: "spam", Body: []byte(`""`)})).To(Succeed())
+				}
+
+				Eventually(reasons).Should(Receive(Equal(reason)))
+				Expect(atomic.LoadInt32(&served)).To(BeZero())
+			})
+		})
+	})
+
+	Context("when many handlers write concurrently", func() {
+		It("delivers all responses", func() {
+			router.On("message", func(w pho.SocketWriter, req *pho.Request) {
+				wg := &sync.WaitGroup{}
+
+				for i := 0; i < 10; i++ {
+					wg.Add(1)
+
+					go func() {
+						defer GinkgoRecover()
+						defer wg.Done()
+
+						for j := 0; j < 10; j++ {
+							Expect(w.Write("message", http.StatusOK, req.Body)).To(Succeed())
+						}
+					}()
+				}
+
+				wg.Wait()
+			})
+
+			client, err := pho.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+			Expect(err).To(BeNil())
+			defer client.Close()
+
+			mu := &sync.Mutex{}
+			cnt := 0
+
+			client.On("message", func(resp *pho.Response) {
+				defer GinkgoRecover()
+				Expect(string(resp.Payload)).To(Equal(`"hello"`))
+
+				mu.Lock()
+				cnt++
+				mu.Unlock()
+			})
+
+			Expect(client.Write("message", []byte(`"hello"`))).To(Succeed())
+			Eventually(func() int {
+				mu.Lock()
+				defer mu.Unlock()
+				return cnt
+			}).Should(Equal(100))
+		})
+	})
+
+	Context("when the socket is closed", func() {
+		It("returns an error on write", func() {
+			sockets := make(chan pho.SocketWriter, 1)
+			router.OnConnect(func(w pho.SocketWriter, req *http.Request) {
+				sockets <- w
+			})
+
+			client, err := pho.Dial(fmt.Sprintf("ws://%s", server.Listener.Addr().String()), nil)
+			Expect(err).To(BeNil())
+
+			defer client.Close()
+
+			socket := <-sockets
+			router.Close()
+			Expect(client.Write("message", []byte(`""`))).To(Succeed())
+
+			Eventually(func() error {
+				return socket.Write("message", http.StatusOK, []byte(`""`))
+			}).Should(Equal(pho.ErrSocketClosed))
+		})
+	})
 })
+
+type headerError struct{}
+
+func (e *headerError) Error() string {
+	return "slow down"
+}
+
+func (e *headerError) Header() pho.Header {
+	return pho.Header{"Retry-After": "1"}
+}
+
+type fieldsError struct{}
+
+func (e *fieldsError) Error() string {
+	return "invalid body"
+}
+
+func (e *fieldsError) Fields() []pho.FieldError {
+	return []pho.FieldError{{Field: "name", Message: "required"}}
+}
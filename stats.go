@@ -0,0 +1,33 @@
+package pho
+
+// MuxStats is a snapshot of the mux statistics
+type MuxStats struct {
+	// ActiveSockets is the number of connected sockets
+	ActiveSockets int
+	// Connects is the number of sockets connected since the mux was created
+	Connects uint64
+	// Disconnects is the number of sockets disconnected since the mux was
+	// created
+	Disconnects uint64
+	// QueuedMessages is the number of messages waiting in the write queues
+	// of the sockets
+	QueuedMessages int
+}
+
+// Stats returns a snapshot of the mux statistics
+func (m *Mux) Stats() MuxStats {
+	m.rw.RLock()
+	defer m.rw.RUnlock()
+
+	stats := MuxStats{
+		ActiveSockets: len(m.sockets),
+		Connects:      m.connects,
+		Disconnects:   m.disconnects,
+	}
+
+	for _, socket := range m.sockets {
+		stats.QueuedMessages += len(socket.(*Socket).queue)
+	}
+
+	return stats
+}
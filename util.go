@@ -4,7 +4,12 @@ import (
 	"crypto/rand"
 )
 
-const MetadataSocketKey = "MetadataSocketKey"
+const (
+	MetadataSocketKey     = "MetadataSocketKey"
+	MetadataDisconnectKey = "MetadataDisconnectKey"
+	MetadataCodecKey      = "MetadataCodecKey"
+	MetadataPrincipalKey  = "MetadataPrincipalKey"
+)
 
 // RandString generates a random string used to assigne Socket ID
 func RandString(length int) (string, error) {
@@ -48,7 +53,14 @@ func Copy(ws WebSockets) WebSockets {
 	return copy
 }
 
-// Sockets returns all availble sockets
+// Sockets returns all availble sockets. The socket of the writer is the
+// writer itself, so the responses written to it answer the request.
 func Sockets(w SocketWriter) WebSockets {
 	return w.Metadata()[MetadataSocketKey].(WebSockets)
 }
+
+// DisconnectReason returns the reason why the socket was disconnected
+func DisconnectReason(w SocketWriter) error {
+	err, _ := w.Metadata()[MetadataDisconnectKey].(error)
+	return err
+}
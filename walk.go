@@ -0,0 +1,108 @@
+package pho
+
+import "sort"
+
+// Routes is implemented by the routers whose routes can be listed
+type Routes interface {
+	// Routes returns the routes registered on the router
+	Routes() []Route
+
+	// Middlewares returns the middlewares of the router
+	Middlewares() []MiddlewareFunc
+}
+
+// Route describes a route registered on a router
+type Route struct {
+	// Verb is the verb or the pattern of the route (ex. "document:{id}")
+	Verb string
+	// Handler serves the route
+	Handler Handler
+	// Middlewares are the inline middlewares of the route (see Mux.With)
+	Middlewares []MiddlewareFunc
+	// SubRoutes are the routes of the router mounted along the verb
+	SubRoutes Routes
+}
+
+// WalkFunc is called for every route visited by Walk. The verb is the full
+// verb of the route and the middlewares are the ones applied to the handler
+// from the outermost to the innermost.
+type WalkFunc func(verb string, handler Handler, middlewares []MiddlewareFunc) error
+
+// Walk walks the routes of the router and its mounted routers in the order
+// of matching. It stops at the first error returned by fn.
+func Walk(router Routes, fn WalkFunc) error {
+	return walk(router, fn, "", nil)
+}
+
+func walk(router Routes, fn WalkFunc, namespace string, parent []MiddlewareFunc) error {
+	middlewares := join(parent, router.Middlewares())
+
+	for _, route := range router.Routes() {
+		stack := join(middlewares, route.Middlewares)
+
+		if route.SubRoutes != nil {
+			if err := walk(route.SubRoutes, fn, namespace+route.Verb+":", stack); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := fn(namespace+route.Verb, route.Handler, stack); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// join returns a new slice with the middlewares of both stacks
+func join(stack, middlewares []MiddlewareFunc) []MiddlewareFunc {
+	result := make([]MiddlewareFunc, 0, len(stack)+len(middlewares))
+	result = append(result, stack...)
+	return append(result, middlewares...)
+}
+
+// Routes returns the routes registered on the mux in the order of matching:
+// the exact verbs and the mounted routers sorted by verb followed by the
+// pattern routes in the order of precedence
+func (m *Mux) Routes() []Route {
+	verbs := make([]string, 0, len(m.handlers))
+	for verb := range m.handlers {
+		verbs = append(verbs, verb)
+	}
+	sort.Strings(verbs)
+
+	routes := make([]Route, 0, len(verbs)+len(m.patterns))
+
+	for _, verb := range verbs {
+		routes = append(routes, newRoute(verb, m.handlers[verb]))
+	}
+
+	for _, pattern := range m.patterns {
+		routes = append(routes, newRoute(pattern.verb, pattern.handler))
+	}
+
+	return routes
+}
+
+// Middlewares returns the middlewares of the mux
+func (m *Mux) Middlewares() []MiddlewareFunc {
+	return m.middlewares
+}
+
+// newRoute describes the handler. The inline handlers are unwrapped, so the
+// route has the inline middlewares and the handler registered by the user.
+func newRoute(verb string, handler Handler) Route {
+	route := Route{Verb: verb, Handler: handler}
+
+	if inline, ok := handler.(*inlineHandler); ok {
+		route.Handler = inline.handler
+		route.Middlewares = inline.router.middlewares
+	}
+
+	if routes, ok := route.Handler.(Routes); ok {
+		route.SubRoutes = routes
+	}
+
+	return route
+}
@@ -0,0 +1,132 @@
+package pho_test
+
+import (
+	"errors"
+
+	"github.com/svett/pho"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Walk", func() {
+	var router *pho.Mux
+
+	noop := func(w pho.SocketWriter, r *pho.Request) {}
+
+	middleware := func(next pho.Handler) pho.Handler {
+		return next
+	}
+
+	type visit struct {
+		verb        string
+		middlewares int
+	}
+
+	walk := func(router pho.Routes) []visit {
+		visits := []visit{}
+
+		err := pho.Walk(router, func(verb string, handler pho.Handler, middlewares []pho.MiddlewareFunc) error {
+			Expect(handler).NotTo(BeNil())
+			visits = append(visits, visit{verb: verb, middlewares: len(middlewares)})
+			return nil
+		})
+
+		Expect(err).To(BeNil())
+		return visits
+	}
+
+	BeforeEach(func() {
+		router = pho.NewMux()
+	})
+
+	It("visits the routes in the order of matching", func() {
+		router.On("*", noop)
+		router.On("user:{id}", noop)
+		router.On("user:me", noop)
+		router.On("auth", noop)
+
+		Expect(walk(router)).To(Equal([]visit{
+			{verb: "auth"},
+			{verb: "user:me"},
+			{verb: "user:{id}"},
+			{verb: "*"},
+		}))
+	})
+
+	It("visits the routes of the mounted routers", func() {
+		router.Use(middleware)
+		router.On("auth", noop)
+
+		router.Route("org:project", func(r pho.Router) {
+			r.Use(middleware)
+			r.On("create", noop)
+			r.On("{id}:update", noop)
+		})
+
+		documents := pho.NewMux()
+		documents.On("open", noop)
+		router.Mount("org:document", documents)
+
+		Expect(walk(router)).To(Equal([]visit{
+			{verb: "auth", middlewares: 1},
+			{verb: "org:document:open", middlewares: 1},
+			{verb: "org:project:create", middlewares: 2},
+			{verb: "org:project:{id}:update", middlewares: 2},
+		}))
+	})
+
+	It("visits the mounted handlers", func() {
+		router.Mount("org:project:doc:update", pho.HandlerFunc(noop))
+
+		Expect(walk(router)).To(Equal([]visit{
+			{verb: "org:project:doc:update"},
+		}))
+	})
+
+	It("provides the inline middlewares", func() {
+		router.Use(middleware)
+		router.With(middleware, middleware).On("secure", noop)
+		router.Group(func(r pho.Router) {
+			r.Use(middleware)
+			r.Route("admin", func(r pho.Router) {
+				r.On("delete", noop)
+			})
+		})
+
+		Expect(walk(router)).To(Equal([]visit{
+			{verb: "admin:delete", middlewares: 2},
+			{verb: "secure", middlewares: 3},
+		}))
+	})
+
+	It("lists the routes of the mux", func() {
+		router.On("auth", noop)
+		router.Route("document", func(r pho.Router) {
+			r.On("open", noop)
+		})
+
+		routes := router.Routes()
+		Expect(routes).To(HaveLen(2))
+		Expect(routes[0].Verb).To(Equal("auth"))
+		Expect(routes[0].SubRoutes).To(BeNil())
+		Expect(routes[1].Verb).To(Equal("document"))
+		Expect(routes[1].SubRoutes).NotTo(BeNil())
+		Expect(routes[1].SubRoutes.Routes()).To(HaveLen(1))
+	})
+
+	It("stops at the first error", func() {
+		router.On("auth", noop)
+		router.On("user", noop)
+
+		visited := 0
+
+		err := pho.Walk(router, func(verb string, handler pho.Handler, middlewares []pho.MiddlewareFunc) error {
+			visited++
+			return errors.New("oh no")
+		})
+
+		Expect(err).To(MatchError("oh no"))
+		Expect(visited).To(Equal(1))
+	})
+})